@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peterbueschel/k8sctx"
+)
+
+// defaultParallel is the number of commands "ktx each" runs at the same time.
+const defaultParallel = 4
+
+var errNoMatch = errors.New("no context matches the selector")
+
+type (
+	// eachOptions are the parsed arguments of "ktx each".
+	eachOptions struct {
+		parallel int
+		json     bool
+		selector []string
+		command  []string
+	}
+	// eachResult is the outcome of the command for a single context.
+	eachResult struct {
+		Context    string `json:"context"`
+		KubeConfig string `json:"kubeconfig"`
+		ExitCode   int    `json:"exitCode"`
+		Stdout     string `json:"stdout,omitempty"`
+		Stderr     string `json:"stderr,omitempty"`
+		Error      string `json:"error,omitempty"`
+		Duration   string `json:"duration"`
+	}
+	// prefixWriter writes complete lines with a prefix into w. All writers of
+	// a run share the mutex, so that the lines of parallel commands are not
+	// mixed up.
+	prefixWriter struct {
+		mu     *sync.Mutex
+		w      io.Writer
+		prefix string
+		buf    []byte
+	}
+)
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		idx := bytes.IndexByte(p.buf, '\n')
+		if idx < 0 {
+			return len(b), nil
+		}
+		p.write(p.buf[:idx+1])
+		p.buf = p.buf[idx+1:]
+	}
+}
+
+// Flush writes the last line, if it doesn't end with a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.write(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) write(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+}
+
+// parseEach parses the arguments of "ktx each":
+//
+//	ktx each [-p|-parallel <n>] [-j|-json] [selector...] -- <command> [args...]
+func parseEach(args []string) (eachOptions, error) {
+	opts := eachOptions{parallel: defaultParallel}
+	for idx := 0; idx < len(args); idx++ {
+		switch arg := args[idx]; arg {
+		case "-p", "-parallel":
+			if idx+1 >= len(args) {
+				return opts, fmt.Errorf("missing value for '%s'", arg)
+			}
+			idx++
+			n, err := strconv.Atoi(args[idx])
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("invalid value for '%s': '%s'", arg, args[idx])
+			}
+			opts.parallel = n
+		case "-j", "-json":
+			opts.json = true
+		case "--":
+			opts.command = args[idx+1:]
+			if len(opts.command) == 0 {
+				return opts, errMissingCommand
+			}
+			return opts, nil
+		default:
+			opts.selector = append(opts.selector, arg)
+		}
+	}
+	return opts, errMissingCommand
+}
+
+// runEach runs a command once per selected context, like:
+//
+//	ktx each environment=prod -- kubectl get nodes
+//
+// The kube config files and the .state file are not changed. If the command
+// failed for at least one context, an exitError is returned.
+func runEach(args []string) (string, error) {
+	opts, err := parseEach(args)
+	if err != nil {
+		return "", err
+	}
+	c, err := peekConfigs()
+	if err != nil {
+		return "", err
+	}
+	selected, err := c.Select(opts.selector)
+	if err != nil {
+		return "", err
+	}
+	if len(selected) == 0 {
+		return "", errNoMatch
+	}
+	if err := confirmSelected(selected); err != nil {
+		return "", err
+	}
+	for _, s := range selected {
+		printBanner(s.KubeConf, s.Context.Name)
+	}
+	if failed := each(c, selected, opts, os.Stdout, os.Stderr); failed > 0 {
+		return "", &exitError{code: 1}
+	}
+	return "", nil
+}
+
+// each runs the command for all selected contexts with at most
+// opts.parallel commands at the same time. The output of the commands is
+// written with the context as prefix, followed by a summary table. In JSON
+// mode, the results are written as a JSON list instead. It returns the number
+// of failed commands.
+func each(c *k8sctx.Config, selected []k8sctx.SelectedContext, opts eachOptions, stdout, stderr io.Writer) int {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		sem     = make(chan struct{}, opts.parallel)
+		results = make([]eachResult, len(selected))
+	)
+	for idx, s := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var out, errOut io.Writer
+			if opts.json {
+				out, errOut = &bytes.Buffer{}, &bytes.Buffer{}
+			} else {
+				prefix := "[" + s.QualifiedName() + "] "
+				out = &prefixWriter{mu: &mu, w: stdout, prefix: prefix}
+				errOut = &prefixWriter{mu: &mu, w: stderr, prefix: prefix}
+			}
+			results[idx] = runIn(c, s, opts.command, out, errOut)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed++
+		}
+	}
+	if opts.json {
+		out, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Fprintln(stdout, string(out))
+		return failed
+	}
+	w := tabwriter.NewWriter(stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nCONTEXT\tKUBECONFIG\tSTATUS\tEXIT CODE\tDURATION")
+	for _, r := range results {
+		status := "ok"
+		if r.ExitCode != 0 {
+			status = "failed"
+		}
+		if r.Error != "" {
+			status = r.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", r.Context, r.KubeConfig, status, r.ExitCode, r.Duration)
+	}
+	w.Flush()
+	return failed
+}
+
+// runIn runs the command against a temporary kube config, which holds only
+// the selected context.
+func runIn(c *k8sctx.Config, s k8sctx.SelectedContext, command []string, stdout, stderr io.Writer) (r eachResult) {
+	r = eachResult{Context: s.QualifiedName(), KubeConfig: s.KubeConf.Alias}
+	start := time.Now()
+	defer func() {
+		r.Duration = time.Since(start).Round(time.Millisecond).String()
+		for _, w := range []io.Writer{stdout, stderr} {
+			switch w := w.(type) {
+			case *prefixWriter:
+				w.Flush()
+			case *bytes.Buffer:
+				if w == stdout {
+					r.Stdout = w.String()
+				} else {
+					r.Stderr = w.String()
+				}
+			}
+		}
+	}()
+
+	path, remove, err := c.Isolate(s.KubeConf, s.Context.Name)
+	if err != nil {
+		r.ExitCode, r.Error = 1, err.Error()
+		return r
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.Env = childEnv(map[string]string{
+		"KUBECONFIG": path,
+		contextEnv:   s.QualifiedName(),
+	})
+	err = errors.Join(runChild(cmd), remove())
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		r.ExitCode = exitErr.ExitCode()
+		if r.ExitCode < 0 {
+			r.ExitCode, r.Error = 1, exitErr.Error()
+		}
+	case err != nil:
+		r.ExitCode, r.Error = 1, err.Error()
+	}
+	return r
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseEach(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    eachOptions
+		wantErr bool
+	}{
+		{
+			name: "positive",
+			args: []string{"-p", "2", "-json", "environment=prod", "t", "--", "kubectl", "get", "pods"},
+			want: eachOptions{
+				parallel: 2,
+				json:     true,
+				selector: []string{"environment=prod", "t"},
+				command:  []string{"kubectl", "get", "pods"},
+			},
+		},
+		{
+			name: "positive - defaults",
+			args: []string{"--", "kubectl", "--context", "x"},
+			want: eachOptions{parallel: defaultParallel, command: []string{"kubectl", "--context", "x"}},
+		},
+		{name: "negative - missing separator", args: []string{"t", "kubectl"}, wantErr: true},
+		{name: "negative - missing command", args: []string{"t", "--"}, wantErr: true},
+		{name: "negative - invalid parallel", args: []string{"-p", "0", "--", "kubectl"}, wantErr: true},
+		{name: "negative - missing parallel", args: []string{"-p"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEach(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_each(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh as command")
+	}
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	t.Cleanup(func() { os.RemoveAll("testdata/isolated") })
+	c, err := loadConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected, err := c.Select([]string{"t", "aws:*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fails only for the prod context
+	script := `echo "$KTX_CONTEXT"; printf partial >&2; case "$KTX_CONTEXT" in *prod*) exit 3;; esac`
+	command := []string{"sh", "-c", script}
+
+	t.Run("prefix", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		failed := each(c, selected, eachOptions{parallel: 1, command: command}, &stdout, &stderr)
+		assert.Equal(t, 1, failed)
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		sort.Strings(lines)
+		assert.Equal(t, []string{
+			"[t/aws:dev:accountId:eu-central-1:cluster1] t/aws:dev:accountId:eu-central-1:cluster1",
+			"[t/aws:prod:accountId:us-east-1:cluster1] t/aws:prod:accountId:us-east-1:cluster1",
+		}, lines)
+		assert.Contains(t, stderr.String(), "[t/aws:dev:accountId:eu-central-1:cluster1] partial\n")
+		assert.Regexp(t, `\nt/aws:prod:accountId:us-east-1:cluster1\s+t\s+failed\s+3`, stderr.String())
+		assert.Regexp(t, `\nt/aws:dev:accountId:eu-central-1:cluster1\s+t\s+ok\s+0`, stderr.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		failed := each(c, selected, eachOptions{parallel: 2, json: true, command: command}, &stdout, &stderr)
+		assert.Equal(t, 1, failed)
+		assert.Empty(t, stderr.String())
+		results := []eachResult{}
+		if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, results, 2) {
+			assert.Equal(t, "t/aws:dev:accountId:eu-central-1:cluster1", results[0].Context)
+			assert.Equal(t, "t/aws:dev:accountId:eu-central-1:cluster1\n", results[0].Stdout)
+			assert.Equal(t, "partial", results[0].Stderr)
+			assert.Equal(t, 0, results[0].ExitCode)
+			assert.Equal(t, 3, results[1].ExitCode)
+		}
+	})
+
+	t.Run("command not found", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		opts := eachOptions{parallel: 1, json: true, command: []string{"ktx-does-not-exist"}}
+		assert.Equal(t, 2, each(c, selected, opts, &stdout, &stderr))
+		results := []eachResult{}
+		if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEmpty(t, results[0].Error)
+	})
+
+	entries, _ := os.ReadDir("testdata/isolated")
+	assert.Empty(t, entries, "temporary kube configs were not removed")
+}
+
+func Test_runEach(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	_, err := runEach([]string{"environment=none", "--", "true"})
+	assert.ErrorIs(t, err, errNoMatch)
+	_, err = runEach([]string{"[", "--", "true"})
+	assert.ErrorIs(t, err, k8sctx.ErrSelector)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+var errMissingCommand = errors.New("missing command, use: ktx exec <context alias> -- <command>")
+
+// exitError is returned if a child process failed. ktx exits with the same
+// exit code.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// execIn runs a command against a temporary kube config, which holds only
+// the given context, like:
+//
+//	ktx exec prod -- kubectl get pods
+//
+// The kube config files and the .state file are not changed. A failed
+// command is returned as exitError.
+func execIn(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errMissingContext
+	}
+	context, command := args[0], args[1:]
+	if len(command) > 0 && command[0] == "--" {
+		command = command[1:]
+	}
+	if len(command) == 0 {
+		return "", errMissingCommand
+	}
+	c, err := peekConfigs()
+	if err != nil {
+		return "", err
+	}
+	kcnf, ctx, err := c.LookupContext(context)
+	if err != nil {
+		return "", err
+	}
+	if err := confirmProtected(kcnf, ctx.Name); err != nil {
+		return "", err
+	}
+	printBanner(kcnf, ctx.Name)
+	path, remove, err := c.Isolate(kcnf, ctx.Name)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = childEnv(map[string]string{
+		"KUBECONFIG": path,
+		contextEnv:   context,
+	})
+	err = runChild(cmd)
+	if rerr := remove(); rerr != nil {
+		return "", errors.Join(err, rerr)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if code < 0 {
+			// killed by a signal
+			code = 1
+		}
+		return "", &exitError{code: code}
+	}
+	if err != nil {
+		return "", fmt.Errorf("run '%s' in context '%s': %w", command[0], context, err)
+	}
+	return "", nil
+}
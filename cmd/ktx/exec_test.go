@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_execIn(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh as command")
+	}
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	t.Cleanup(func() { os.RemoveAll("testdata/isolated") })
+	kubeConfig, err := os.ReadFile("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := os.ReadFile("testdata/.state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	script := `echo "$KTX_CONTEXT" > ` + out + `; grep -q "current-context: $KTX_CONTEXT" "$KUBECONFIG" && exit 7`
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  error
+	}{
+		{
+			name:     "positive - exit code passes through",
+			args:     []string{"aws:dev:accountId:eu-central-1:cluster1", "--", "sh", "-c", script},
+			wantCode: 7,
+		},
+		{
+			name:     "positive - without separator",
+			args:     []string{"aws:dev:accountId:eu-central-1:cluster1", "sh", "-c", "true"},
+			wantCode: 0,
+		},
+		{
+			name:    "negative - missing command",
+			args:    []string{"aws:dev:accountId:eu-central-1:cluster1", "--"},
+			wantErr: errMissingCommand,
+		},
+		{
+			name:    "negative - missing context",
+			args:    []string{},
+			wantErr: errMissingContext,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := execIn(tt.args)
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.wantCode != 0:
+				assert.Equal(t, &exitError{code: tt.wantCode}, err)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "aws:dev:accountId:eu-central-1:cluster1\n", string(got))
+	entries, _ := os.ReadDir("testdata/isolated")
+	assert.Empty(t, entries, "temporary kube configs were not removed")
+
+	unchanged, err := os.ReadFile("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(kubeConfig), string(unchanged), "kube config was changed")
+	unchanged, err = os.ReadFile("testdata/.state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(state), string(unchanged), "state was changed")
+}
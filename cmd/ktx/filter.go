@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fieldAliases are short keys of the structured filter, which don't work as
+// prefix of the field.
+var fieldAliases = map[string]string{"ns": "namespace"}
+
+// filterTerm is a structured term of the filter, like "env:prod". It matches
+// the fields, whose name starts with the key and whose value contains the
+// value, ignoring the case.
+type filterTerm struct {
+	key, value string
+}
+
+// parseFilter splits the filter into the structured terms and the remaining
+// text for the fuzzy search. A "key:value" term is only structured, if the
+// key is the prefix of a field of an item, so that context names like
+// "aws:prod" are still searched as text.
+func parseFilter(filter string, items []list.Item) ([]filterTerm, string) {
+	terms := []filterTerm{}
+	text := []string{}
+	for _, word := range strings.Fields(filter) {
+		key, value, ok := strings.Cut(word, ":")
+		if ok && key != "" && hasField(items, key) {
+			terms = append(terms, filterTerm{key: strings.ToLower(key), value: strings.ToLower(value)})
+			continue
+		}
+		text = append(text, word)
+	}
+	return terms, strings.Join(text, " ")
+}
+
+// hasField returns true if an item has a field starting with the key.
+func hasField(items []list.Item, key string) bool {
+	for _, li := range items {
+		if i, ok := li.(item); ok && len(i.matchingFields(key)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingFields returns the names of the fields, which start with the key
+// or which are named by an alias like "ns".
+func (i item) matchingFields(key string) []string {
+	key = strings.ToLower(key)
+	names := []string{}
+	for name := range i.fields {
+		if strings.HasPrefix(strings.ToLower(name), key) || fieldAliases[key] == name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matches returns true if the item matches all structured terms.
+func (i item) matches(terms []filterTerm) bool {
+	for _, t := range terms {
+		found := false
+		for _, name := range i.matchingFields(t.key) {
+			if strings.Contains(strings.ToLower(i.fields[name]), t.value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// highlights returns the indexes of the values of the structured terms in
+// the filter value of the item.
+func (i item) highlights(terms []filterTerm) []int {
+	value := []rune(strings.ToLower(i.FilterValue()))
+	start := len([]rune(i.title)) + 1
+	indexes := []int{}
+	for _, t := range terms {
+		v := []rune(t.value)
+		if len(v) == 0 {
+			continue
+		}
+		for idx := start; idx+len(v) <= len(value); idx++ {
+			if string(value[idx:idx+len(v)]) == string(v) {
+				for n := range v {
+					indexes = append(indexes, idx+n)
+				}
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// newFilter returns the filter of the list of the items. Next to the fuzzy
+// search over the title, the description and the context name, it supports
+// structured terms like "env:prod region:us ns:monitoring" and a namespace
+// behind the context like "lab/monitoring" (see splitFilter).
+func newFilter(items []list.Item) list.FilterFunc {
+	names := titles(items)
+	return func(term string, targets []string) []list.Rank {
+		if len(targets) != len(items) {
+			return list.DefaultFilter(term, targets)
+		}
+		term, _ = splitFilter(term, names)
+		terms, text := parseFilter(term, items)
+		candidates, indexes := []string{}, []int{}
+		for idx, target := range targets {
+			if i, ok := items[idx].(item); ok && !i.matches(terms) {
+				continue
+			}
+			candidates = append(candidates, target)
+			indexes = append(indexes, idx)
+		}
+		ranks := []list.Rank{}
+		if text == "" {
+			for _, idx := range indexes {
+				ranks = append(ranks, list.Rank{Index: idx})
+			}
+		} else {
+			ranks = list.DefaultFilter(text, candidates)
+			for n := range ranks {
+				ranks[n].Index = indexes[ranks[n].Index]
+			}
+		}
+		for n, r := range ranks {
+			if i, ok := items[r.Index].(item); ok {
+				ranks[n].MatchedIndexes = append(ranks[n].MatchedIndexes, i.highlights(terms)...)
+			}
+		}
+		return ranks
+	}
+}
+
+// contextDelegate renders the matches of the filter in the title and the
+// description and the protected contexts in a warning style.
+type contextDelegate struct {
+	list.DefaultDelegate
+	warning list.DefaultItemStyles
+}
+
+func newContextDelegate(d list.DefaultDelegate) contextDelegate {
+	warning := d.Styles
+	warning.NormalTitle = warning.NormalTitle.Foreground(protectedTitle.GetForeground())
+	warning.SelectedTitle = warning.SelectedTitle.
+		Foreground(protectedTitle.GetForeground()).
+		BorderLeftForeground(protectedTitle.GetForeground())
+	warning.SelectedDesc = warning.SelectedDesc.BorderLeftForeground(protectedTitle.GetForeground())
+	return contextDelegate{DefaultDelegate: d, warning: warning}
+}
+
+func (d contextDelegate) Render(w io.Writer, m list.Model, index int, li list.Item) {
+	i, ok := li.(item)
+	if !ok || m.Width() <= 0 {
+		d.DefaultDelegate.Render(w, m, index, li)
+		return
+	}
+	s := d.Styles
+	if i.protected {
+		s = d.warning
+	}
+	width := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
+	title, desc := truncate(i.title, width), truncate(i.description, width)
+
+	var (
+		isFiltered  = m.FilterState() == list.Filtering || m.FilterState() == list.FilterApplied
+		emptyFilter = m.FilterState() == list.Filtering && m.FilterValue() == ""
+		isSelected  = index == m.Index() && m.FilterState() != list.Filtering
+		titleStyle  = s.NormalTitle
+		descStyle   = s.NormalDesc
+	)
+	switch {
+	case emptyFilter:
+		titleStyle, descStyle, isFiltered = s.DimmedTitle, s.DimmedDesc, false
+	case isSelected:
+		titleStyle, descStyle = s.SelectedTitle, s.SelectedDesc
+	}
+	if isFiltered && index < len(m.VisibleItems()) {
+		titleMatches, descMatches := splitMatches(m.MatchesForItem(index), len([]rune(i.title)))
+		title = highlight(title, titleMatches, titleStyle, s.FilterMatch)
+		desc = highlight(desc, descMatches, descStyle, s.FilterMatch)
+	}
+	title, desc = titleStyle.Render(title), descStyle.Render(desc)
+	if d.ShowDescription {
+		fmt.Fprintf(w, "%s\n%s", title, desc)
+		return
+	}
+	fmt.Fprint(w, title)
+}
+
+// splitMatches splits the matched indexes of the filter value into the ones
+// of the title and the ones of the description.
+func splitMatches(matches []int, titleLen int) (title, desc []int) {
+	for _, idx := range matches {
+		switch {
+		case idx < titleLen:
+			title = append(title, idx)
+		case idx > titleLen:
+			desc = append(desc, idx-titleLen-1)
+		}
+	}
+	return title, desc
+}
+
+// highlight renders the matched runes of the text in the match style.
+func highlight(text string, matches []int, style, match lipgloss.Style) string {
+	unmatched := style.Inline(true)
+	return lipgloss.StyleRunes(text, matches, unmatched.Inherit(match), unmatched)
+}
+
+// truncate shortens the text to the width with an ellipsis.
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if width <= 0 || len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
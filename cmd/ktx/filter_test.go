@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/stretchr/testify/assert"
+)
+
+func filterItems() []list.Item {
+	return []list.Item{
+		item{
+			title:       "prod",
+			description: "namespace: monitoring, environment: prod, region: us-east-1",
+			context:     "arn:aws:eks:prod",
+			fields:      map[string]string{"name": "arn:aws:eks:prod", "namespace": "monitoring", "environment": "prod", "region": "us-east-1"},
+		},
+		item{
+			title:       "stage",
+			description: "namespace: default, environment: stage, region: eu-west-1",
+			context:     "arn:aws:eks:stage",
+			fields:      map[string]string{"name": "arn:aws:eks:stage", "namespace": "default", "environment": "stage", "region": "eu-west-1"},
+		},
+		item{
+			title:       "minikube",
+			description: "",
+			context:     "minikube",
+			fields:      map[string]string{"name": "minikube"},
+		},
+	}
+}
+
+func Test_parseFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    string
+		wantTerms []filterTerm
+		wantText  string
+	}{
+		{name: "text only", filter: "prod", wantTerms: []filterTerm{}, wantText: "prod"},
+		{
+			name:      "field prefix",
+			filter:    "env:Prod region:us",
+			wantTerms: []filterTerm{{key: "env", value: "prod"}, {key: "region", value: "us"}},
+		},
+		{name: "alias", filter: "ns:mon", wantTerms: []filterTerm{{key: "ns", value: "mon"}}},
+		{
+			name:      "unknown key is text",
+			filter:    "arn:aws env:stage",
+			wantTerms: []filterTerm{{key: "env", value: "stage"}},
+			wantText:  "arn:aws",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, text := parseFilter(tt.filter, filterItems())
+			assert.Equal(t, tt.wantTerms, terms)
+			assert.Equal(t, tt.wantText, text)
+		})
+	}
+}
+
+func Test_newFilter(t *testing.T) {
+	items := filterItems()
+	targets := make([]string, len(items))
+	for idx, i := range items {
+		targets[idx] = i.FilterValue()
+	}
+	tests := []struct {
+		name string
+		term string
+		want []int
+	}{
+		{name: "title", term: "minikube", want: []int{2}},
+		{name: "description", term: "eu-west", want: []int{1}},
+		{name: "context name", term: "eks:stage", want: []int{1}},
+		{name: "structured", term: "env:prod region:us", want: []int{0}},
+		{name: "namespace alias", term: "ns:def", want: []int{1}},
+		{name: "structured and text", term: "env:s stage", want: []int{1}},
+		{name: "no match", term: "env:dev", want: []int{}},
+		{name: "namespace behind the context", term: "minikube/web", want: []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranks := newFilter(items)(tt.term, targets)
+			got := make([]int, len(ranks))
+			for idx, r := range ranks {
+				got[idx] = r.Index
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_newFilter_highlights(t *testing.T) {
+	items := filterItems()
+	targets := make([]string, len(items))
+	for idx, i := range items {
+		targets[idx] = i.FilterValue()
+	}
+	ranks := newFilter(items)("region:us", targets)
+	if assert.Len(t, ranks, 1) {
+		title, desc := splitMatches(ranks[0].MatchedIndexes, len("prod"))
+		assert.Empty(t, title)
+		assert.Equal(t, []int{50, 51}, desc)
+	}
+}
+
+func Test_splitMatches(t *testing.T) {
+	title, desc := splitMatches([]int{0, 2, 4, 5, 7}, 4)
+	assert.Equal(t, []int{0, 2}, title)
+	assert.Equal(t, []int{0, 2}, desc)
+}
+
+func Test_truncate(t *testing.T) {
+	assert.Equal(t, "prod", truncate("prod", 4))
+	assert.Equal(t, "pr…", truncate("prod", 3))
+	assert.Equal(t, "äö…", truncate("äöüß", 3))
+	assert.Equal(t, "prod", truncate("prod", 0))
+}
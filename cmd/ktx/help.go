@@ -6,18 +6,86 @@ ktx - kubernetes context switcher
 
 USAGE:
 
-  ktx [OPTIONS|[config alias [context alias]]]
+  ktx [OPTIONS|[config alias [context alias]]|[selector...]]
 
 OPTIONS:
 
-  [-]                 - Switch to the previous context
+  [-]                 - Switch to the previous context together with its namespace. The previous context
+                        is tracked per terminal (KTX_SESSION or TTY) with a fallback to the global one.
+
+  [-N]                - Jump N steps back in the history of the switches, like "ktx -3".
 
   [-h|-help]          - Shows this help.
   
-  [-c|-is|-current]   - Returns the current context.
+  [-c|-is|-current]   - Returns the current context as read from the kubeconfigs. It warns, if the current
+                        context was changed outside of ktx. Like "list", "exec", "each", "shell" and the
+                        shell completion, it doesn't change any file.
+
+  [-c|-is|-current] <context alias|selector>
+                      - Switches directly to the context. A selector (see SELECTORS) must match exactly
+                        one context.
+
+  [-s|-session] <context alias>
+                      - Switches the context only for the current shell. The kubeconfig files stay
+                        untouched. Like "-c", it takes a namespace (<context alias>/<namespace>) or a
+                        selector and asks before a protected context is used. Prints the shell code
+                        to activate the session; use it via eval "$(ktx -s <context alias>)". Inside
+                        a session "ktx -", "ktx -c" and the TUI change only the context of the session.
+
+  [-s|-session]       - Ends the session of the current shell. Use it via eval "$(ktx -s)".
+
+  [-y|--yes]          - Switches to or uses a protected context without a confirmation. For "shell", "exec"
+                        and "each" put it before the command, like "ktx -y exec prod -- kubectl get pods";
+                        it is not taken from their arguments or behind "--".
 
   [-v|-version]       - Prints the version
 
+  shell <context alias>
+                      - Starts your shell ($SHELL) with a temporary kubeconfig, which holds only the
+                        context with its cluster, user and namespace. The kubeconfig files and the
+                        state stay untouched. The temporary kubeconfig is removed, when the shell exits.
+
+  exec <context alias> [--] <command> [args...]
+                      - Runs the command with a temporary kubeconfig, which holds only the context. The
+                        kubeconfig files and the state stay untouched. ktx exits with the exit code
+                        of the command.
+
+  each [-p|-parallel <n>] [-j|-json] [selector...] -- <command> [args...]
+                      - Runs the command once per selected context (see SELECTORS), each with a temporary
+                        kubeconfig.
+                        Up to 4 commands run in parallel (change it via "-parallel"). The output is prefixed
+                        by the context and followed by a summary table; with "-json" the results are
+                        printed as JSON instead.
+
+  ns [namespace]      - Sets the namespace of the current context. Without a namespace, the namespaces are
+                        read from the cluster with the credentials of the context and shown in a list.
+                        If the cluster cannot be reached, the "namespaces" field (a comma separated list)
+                        of the context in the contexts file is used. Inside a session or an isolated shell,
+                        only the kubeconfig of the shell is changed.
+
+  list [-o|-output <format>] [selector...]
+                      - Prints the contexts, which match the selectors (see SELECTORS), like a kubeconfig
+                        alias. The format is one of "table" (default), "wide", "name", "json" or "yaml".
+                        It shows the cluster, user and namespace of a context and marks the current ("*")
+                        and the previous ("-") one; "wide", "json" and "yaml" add the server, the kubeconfig
+                        and more. Credentials are never printed, secrets in server URLs and fields like
+                        "token" or "password" are redacted.
+
+  history [-l|-list]  - Shows the last switches (up to 50) with their namespaces in the TUI to choose one.
+                        With "-list" the history is printed instead.
+
+  sync [-n|-dry-run] [-j|-json]
+                      - Syncs the contexts files with the kubeconfigs and the namespaces of the contexts
+                        into the kubeconfigs. Prints a report of the changes; with "-dry-run" nothing
+                        is written. Other commands sync only, if one of the files changed since the
+                        last sync. Namespace drifts between a kubeconfig and the config.jsonnet are
+                        resolved by the "namespace_drift" setting; unresolved ones are prompted for.
+
+  init <bash|zsh|fish> [-p|-prompt]
+                      - Prints the shell integration: a "ktx" shell function, which evaluates the
+                        shell code of "ktx -s" on its own, and the completion of the aliases.
+                        With "-prompt" the current context is added to the prompt.
+
 ALIASES:
   
   [config alias]      - Shows only the contexts of one kubeconfigs (given by its alias).
@@ -26,12 +94,49 @@ ALIASES:
   [context alias]     - Directly switch to the context (given by its alias)
                         (DEFAULT: <no filter>) 
 
+                        If the same context name/alias exists in more than one kubeconfig, qualify it
+                        with the config alias, like "dev/minikube".
+
+                        Add a namespace behind the context, like "lab/monitoring", to switch context and
+                        namespace in one step (also in the filter of the TUI). Without a namespace, the
+                        last used namespace of the context is set again.
+
+  [selector...]       - Shows only the contexts matching the selectors (see SELECTORS), like
+                        ktx 'environment in (prod,stage)'.
+
+
+SELECTORS:
+
+  Selectors follow the label selectors of Kubernetes and match the fields of the contexts files. All
+  selectors must match; several can be joined by commas, like 'environment in (prod,stage),region!=us-east-1'.
+
+  key=value, key==value
+                      - The field has the value. The key "config" matches the kubeconfig alias.
+  key!=value          - The field has another value or doesn't exist.
+  key in (a,b)        - The field has one of the values.
+  key notin (a,b)     - The field has none of the values or doesn't exist.
+  !key                - The field doesn't exist.
+  alias               - A kubeconfig alias or a glob on the context name/alias, like "prod-*".
+
+  The values can be globs, too. A "*" matches "/" as well, like "*/prod" for "arn:aws:eks:...:cluster/prod".
 
 ENVIRONMENT VARIABLES:
 
   KTX_CONFIG_DIR      - Specify the directory for the "ktx" config files.
                         (DEFAULT: is OS related directory like "$HOME/.config/ktx" or "%AppData%\ktx")
 
+  KTX_SESSION         - The id of the session of the current shell. It is set by "ktx -s". If set, it
+                        also identifies the terminal for "ktx -".
+
+  KTX_CONTEXT         - The context of a shell started by "ktx shell" or a command run by "ktx exec|each".
+
+  KTX_SHELL_LEVEL     - The nesting level of shells started by "ktx shell".
+
+  KTX_SHELL           - Set by the "ktx" shell function (see "ktx init") to the name of the shell.
+
+  KTX_SHELL_PID       - Set by the "ktx" shell function to the process id of the shell. It is stored
+                        with a session to remove its files after the shell exited.
+
 
 FILES:
 
@@ -45,9 +150,22 @@ FILES:
                         "contexts_<...>.yaml" file. It also makes these contents available under the alias
                         of the related kubeconfig. This file doesn't need to be touched.
 
-  .state              - The state file stores the last used kubeconfig and context together with the current
-                        ones. This file is required for the "ktx -" command in order to jump back and forth
-                        between two contexts.
+  isolated/           - Holds the temporary kubeconfigs of "ktx shell", "ktx exec" and "ktx each".
+
+  sessions/           - Holds a small kubeconfig and a state file per session (see "ktx -s"). The files
+                        of sessions, whose shell exited without "ktx -s", are removed, when a new
+                        session starts.
+
+  .sync               - Holds the fingerprint of the config files and kubeconfigs of the last sync.
+
+  .lock               - Is locked by ktx while it changes the kubeconfigs, the contexts files or the
+                        state files, so that concurrent ktx runs don't overwrite each other's changes.
+
+  .state              - The state file stores the last used kubeconfig, context and namespace together with
+                        the current ones, the last used namespace of every context, the history of the
+                        switches and the cluster and user of every context to detect renamed ones. Files of
+                        older ktx versions are migrated automatically. This file is required for the "ktx -"
+                        command in order to jump back and forth between two contexts.
 
 EXAMPLES:
 
@@ -67,12 +185,56 @@ EXAMPLES:
 
   ktx -
 
+- Jumps back to the context used two switches ago:
+
+  ktx -2
+
 - Switches directly to the context with the name/alias "lab":
 
   ktx -c lab
 
+- Switches directly to the context with the name/alias "lab" and its namespace "monitoring":
+
+  ktx -c lab/monitoring
+
+- Switches directly to the only production context in the region "eu-west-1":
+
+  ktx -c 'environment=prod,region=eu-west-1'
+
+- Lists all production and staging contexts outside of the region "us-east-1":
+
+  ktx list 'environment in (prod,stage),region!=us-east-1'
+
+- Prints all contexts of the kubeconfig with the alias "m" as JSON:
+
+  ktx list -o json m
+
 - Returns the current context:
 
   ktx -c
+
+- Switches to the context with the name/alias "lab" only in the current shell:
+
+  eval "$(ktx -s lab)"
+
+- Sets the namespace "monitoring" for the current context:
+
+  ktx ns monitoring
+
+- Starts a new shell, which uses only the context with the name/alias "prod":
+
+  ktx shell prod
+
+- Lists the pods of the context with the name/alias "prod" without switching to it:
+
+  ktx exec prod -- kubectl get pods
+
+- Lists the nodes of all contexts with "environment: prod" in the contexts files:
+
+  ktx each environment=prod -- kubectl get nodes
+
+- Enables the shell integration for bash (add it to your ~/.bashrc):
+
+  eval "$(ktx init bash)"
 `
 )
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/peterbueschel/k8sctx"
+)
+
+// timeFormat is the format of the timestamps in the history.
+const timeFormat = "2006-01-02 15:04:05"
+
+// steps returns N of the argument "-N".
+func steps(arg string) (int, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(arg[1:])
+	return n, err == nil && n > 0
+}
+
+// jumpBack switches to the context and namespace of the history N steps
+// back. "ktx -1" is the previous context.
+func jumpBack(n int) (string, error) {
+	c, err := loadConfigs()
+	if err != nil {
+		return "", err
+	}
+	sw, state := switcherFor(c)
+	if err := sw.GetState(); err != nil {
+		return "", err
+	}
+	e, err := state.Step(n)
+	if err != nil {
+		return "", err
+	}
+	kcnf := c.GetKubeConfigBy(e.KubeConfig)
+	if kcnf == nil {
+		return "", fmt.Errorf("%w: kube config '%s' of '%s'", k8sctx.ErrNoContext, e.KubeConfig, e.Context)
+	}
+	if err := confirmProtected(kcnf, e.Context); err != nil {
+		return "", err
+	}
+	if err := sw.SwitchToNamespace(kcnf, e.Context, e.Namespace); err != nil {
+		return "", fmt.Errorf("using context of the history failed: %w", err)
+	}
+	printBanner(kcnf, e.Context)
+	return e.Context, nil
+}
+
+// showHistory lets the user choose a context of the history in the TUI. With
+// "-l|-list" the history is printed instead.
+func showHistory(args []string) (string, error) {
+	asList := false
+	for _, arg := range args {
+		switch strings.TrimPrefix(arg, "-") {
+		case "-list", "list", "l":
+			asList = true
+		default:
+			return "", fmt.Errorf("unknown option '%s' for 'ktx history'", arg)
+		}
+	}
+	load := loadConfigs
+	if asList {
+		load = peekConfigs
+	}
+	c, err := load()
+	if err != nil {
+		return "", err
+	}
+	sw, state := switcherFor(c)
+	if err := sw.GetState(); err != nil {
+		return "", err
+	}
+	if asList {
+		return historyList(c, state.History), nil
+	}
+	m := listModel(c, "History", historyItems(c, state.History), false)
+	if _, err := newProgram(m).Run(); err != nil {
+		return "", fmt.Errorf("error running program: %w", err)
+	}
+	printBanner(c.GetKubeConfigByAlias(m.switched.config), m.switched.context)
+	return getCurrentContext()
+}
+
+// historyItems returns the entries of the history as items of the TUI.
+func historyItems(c *k8sctx.Config, history []k8sctx.HistoryEntry) []list.Item {
+	items := make([]list.Item, 0, len(history))
+	for idx, e := range history {
+		title, config := historyTitle(c, e)
+		when := "current"
+		if idx > 0 {
+			when = fmt.Sprintf("-%d", idx)
+		}
+		if !e.Time.IsZero() {
+			when += ", " + e.Time.Local().Format(timeFormat)
+		}
+		kubeConfig := config
+		if kubeConfig == "" {
+			kubeConfig = e.KubeConfig
+		}
+		protected := false
+		fields := map[string]string{}
+		if kcnf := c.GetKubeConfigBy(e.KubeConfig); kcnf != nil {
+			protected = kcnf.Protected(e.Context)
+			if ctx, _ := kcnf.GetContextBy(e.Context); ctx != nil {
+				fields = ctx.Strings()
+			}
+		}
+		if e.Namespace != "" {
+			fields["namespace"] = e.Namespace
+		}
+		items = append(items, item{
+			title:       title,
+			description: fmt.Sprintf("%s, kubeconfig: %s", when, kubeConfig),
+			config:      config,
+			context:     e.Context,
+			namespace:   e.Namespace,
+			protected:   protected,
+			fields:      fields,
+		})
+	}
+	return items
+}
+
+// historyList returns the history as table.
+func historyList(c *k8sctx.Config, history []k8sctx.HistoryEntry) string {
+	out := &strings.Builder{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tCONTEXT\tSWITCHED")
+	for idx, e := range history {
+		title, _ := historyTitle(c, e)
+		when := ""
+		if !e.Time.IsZero() {
+			when = e.Time.Local().Format(timeFormat)
+		}
+		step := fmt.Sprintf("-%d", idx)
+		if idx == 0 {
+			step = "0"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", step, title, when)
+	}
+	w.Flush()
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// historyTitle returns the alias of the context, qualified by the alias of
+// the kube config and followed by the namespace, like "dev/lab/monitoring".
+// The config is empty, if the kube config is no longer part of the config.
+func historyTitle(c *k8sctx.Config, e k8sctx.HistoryEntry) (title, config string) {
+	title = e.Context
+	if kcnf := c.GetKubeConfigBy(e.KubeConfig); kcnf != nil {
+		config = kcnf.Alias
+		if ctx, idx := kcnf.GetContextBy(e.Context); idx != -1 && ctx.Alias != "" {
+			title = ctx.Alias
+		}
+		title = kcnf.QualifiedName(title)
+	}
+	if e.Namespace != "" {
+		title += "/" + e.Namespace
+	}
+	return title, config
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_steps(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   int
+		wantOk bool
+	}{
+		{arg: "-1", want: 1, wantOk: true},
+		{arg: "-12", want: 12, wantOk: true},
+		{arg: "-", wantOk: false},
+		{arg: "-0", wantOk: false},
+		{arg: "--2", wantOk: false},
+		{arg: "-c", wantOk: false},
+		{arg: "3", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, ok := steps(tt.arg)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_historyList(t *testing.T) {
+	c := &k8sctx.Config{KubeConfs: []*k8sctx.KubeConf{
+		{Path: "/kube/dev", Alias: "dev", Contexts: []*k8sctx.ContextEntry{{Name: "arn:lab", Alias: "lab"}}},
+	}}
+	history := []k8sctx.HistoryEntry{
+		{KubeConfig: "/kube/dev", Context: "arn:lab", Namespace: "web", Time: time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)},
+		{KubeConfig: "/kube/gone", Context: "old"},
+	}
+	assert.Equal(t, strings.Join([]string{
+		"STEP  CONTEXT      SWITCHED",
+		"0     dev/lab/web  2024-01-01 10:00:00",
+		"-1    old          ",
+	}, "\n"), historyList(c, history))
+
+	items := historyItems(c, history)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, item{
+			title:       "dev/lab/web",
+			description: "current, 2024-01-01 10:00:00, kubeconfig: dev",
+			config:      "dev",
+			context:     "arn:lab",
+			namespace:   "web",
+			fields:      map[string]string{"alias": "lab", "name": "arn:lab", "namespace": "web"},
+		}, items[0])
+		assert.Equal(t, "-1, kubeconfig: /kube/gone", items[1].(item).description)
+	}
+}
+
+func Test_jumpBack(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv(k8sctx.SessionEnv, "history")
+	t.Cleanup(func() { os.RemoveAll("testdata/sessions") })
+	sessionConfig := filepath.Join("testdata", "sessions", "history.kubeconfig")
+	t.Setenv("KUBECONFIG", sessionConfig+string(os.PathListSeparator)+"testdata/kube.config")
+
+	for _, ctx := range []string{
+		"aws:dev:accountId:eu-central-1:cluster1/web",
+		"aws:prod:accountId:us-east-1:cluster1",
+		"aws:dev:accountId:eu-central-1:cluster1/monitoring",
+	} {
+		if _, err := directlyUse(ctx); err != nil {
+			t.Fatalf("directlyUse() error = %v", err)
+		}
+	}
+	got, err := jumpBack(2)
+	if err != nil {
+		t.Fatalf("jumpBack() error = %v", err)
+	}
+	assert.Equal(t, "aws:dev:accountId:eu-central-1:cluster1", got)
+	assert.Equal(t, "web", sessionNamespace(t, sessionConfig))
+
+	_, err = jumpBack(10)
+	assert.ErrorIs(t, err, k8sctx.ErrHistory)
+
+	// the switch to the same context replaced the newest entry
+	list, err := showHistory([]string{"-l"})
+	assert.NoError(t, err)
+	lines := strings.Split(list, "\n")
+	if assert.Len(t, lines, 4, list) {
+		assert.Contains(t, lines[1], "aws:dev:accountId:eu-central-1:cluster1/web")
+		assert.Contains(t, lines[2], "aws:prod:accountId:us-east-1:cluster1")
+	}
+
+	_, err = showHistory([]string{"-x"})
+	assert.Error(t, err)
+}
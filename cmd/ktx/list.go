@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/peterbueschel/k8sctx"
+	"gopkg.in/yaml.v3"
+)
+
+// listFormats are the output formats of "ktx list".
+var listFormats = []string{"table", "wide", "name", "json", "yaml"}
+
+// listOptions are the parsed arguments of "ktx list".
+type listOptions struct {
+	output   string
+	selector []string
+}
+
+// parseList parses the arguments of "ktx list":
+//
+//	ktx list [-o|-output <format>] [selector...]
+func parseList(args []string) (listOptions, error) {
+	opts := listOptions{output: "table"}
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "-o", "-output", "--output":
+			if !hasValue {
+				if idx+1 >= len(args) {
+					return opts, fmt.Errorf("missing value for '%s'", arg)
+				}
+				idx++
+				value = args[idx]
+			}
+			if !slices.Contains(listFormats, value) {
+				return opts, fmt.Errorf("invalid value for '%s': '%s' (one of %s)",
+					name, value, strings.Join(listFormats, "|"))
+			}
+			opts.output = value
+		default:
+			opts.selector = append(opts.selector, arg)
+		}
+	}
+	return opts, nil
+}
+
+// listContexts prints the contexts, which match the selector, without the
+// TUI, like:
+//
+//	ktx list -o json 'environment in (prod,stage),region!=us-east-1'
+func listContexts(args []string) (string, error) {
+	opts, err := parseList(args)
+	if err != nil {
+		return "", err
+	}
+	c, err := peekConfigs()
+	if err != nil {
+		return "", err
+	}
+	contexts, err := c.SelectListItems(opts.selector)
+	if err != nil {
+		return "", err
+	}
+	if len(contexts) == 0 {
+		return "", errNoMatch
+	}
+	sw, state := switcherFor(c)
+	if err := sw.GetState(); err != nil {
+		return "", err
+	}
+	return formatInventory(c.Inventory(contexts, currentEntry(c, state), state.Previous(c.Terminal)), opts.output)
+}
+
+// currentEntry returns the context, which kubectl uses in this shell: the
+// context of a shell started by "ktx shell", the one of the session or the
+// current context of the kube configs, like "ktx -c" does.
+func currentEntry(c *k8sctx.Config, state *k8sctx.State) k8sctx.HistoryEntry {
+	if name := isolatedContext(c); name != "" {
+		if kcnf, ctx, err := c.LookupContext(name); err == nil {
+			return k8sctx.HistoryEntry{KubeConfig: kcnf.Path, Context: ctx.Name}
+		}
+	}
+	if activeSession(c) == nil {
+		// a switch outside of ktx is not yet recorded in the .state file
+		if k, name := c.ActualContext(kubeConfigsWithout(c)); k != nil {
+			return k8sctx.HistoryEntry{KubeConfig: k.Path, Context: name}
+		}
+	}
+	return k8sctx.HistoryEntry{KubeConfig: state.CurrentConf, Context: state.CurrentContext}
+}
+
+// formatInventory returns the inventory in the output format.
+func formatInventory(entries []k8sctx.InventoryEntry, output string) (string, error) {
+	switch output {
+	case "name":
+		names := make([]string, len(entries))
+		for idx, e := range entries {
+			names[idx] = e.Name
+		}
+		return strings.Join(names, "\n"), nil
+	case "json":
+		out, err := json.MarshalIndent(entries, "", "  ")
+		return string(out), err
+	case "yaml":
+		out, err := yaml.Marshal(entries)
+		return strings.TrimSuffix(string(out), "\n"), err
+	}
+	out := &strings.Builder{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if output == "wide" {
+		fmt.Fprintln(w, "CURRENT\tNAME\tCLUSTER\tUSER\tNAMESPACE\tCONFIG\tSERVER\tKUBECONFIG\tPROTECTED")
+	} else {
+		fmt.Fprintln(w, "CURRENT\tNAME\tCLUSTER\tUSER\tNAMESPACE")
+	}
+	for _, e := range entries {
+		marker := ""
+		switch {
+		case e.Current:
+			marker = "*"
+		case e.Previous:
+			marker = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s", marker, e.Name, e.Cluster, e.User, e.Namespace)
+		if output == "wide" {
+			fmt.Fprintf(w, "\t%s\t%s\t%s\t%t", e.Config, e.Server, e.KubeConfig, e.Protected)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
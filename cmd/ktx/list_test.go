@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_parseList(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    listOptions
+		wantErr bool
+	}{
+		{name: "defaults", want: listOptions{output: "table"}},
+		{
+			name: "output and selector",
+			args: []string{"-o", "json", "t", "environment=prod"},
+			want: listOptions{output: "json", selector: []string{"t", "environment=prod"}},
+		},
+		{name: "output with equal sign", args: []string{"--output=wide"}, want: listOptions{output: "wide"}},
+		{name: "negative - missing output", args: []string{"-o"}, wantErr: true},
+		{name: "negative - unknown output", args: []string{"-output", "xml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseList(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_listContexts(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "all",
+			args: []string{"-o", "name"},
+			want: "aws:dev:accountId:eu-central-1:cluster1\naws:prod:accountId:us-east-1:cluster1",
+		},
+		{
+			name: "selector",
+			args: []string{"-o", "name", "name=aws:dev:*"},
+			want: "aws:dev:accountId:eu-central-1:cluster1",
+		},
+		{
+			name: "kube config alias",
+			args: []string{"-o=name", "t", "aws:prod:*"},
+			want: "aws:prod:accountId:us-east-1:cluster1",
+		},
+		{name: "negative - no match", args: []string{"environment=prod"}, wantErr: errNoMatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KTX_CONFIG_DIR", "testdata")
+			got, err := listContexts(tt.args)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_listContexts_json(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	out, err := listContexts([]string{"-o", "json", "name=aws:dev:*"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	entries := []k8sctx.InventoryEntry{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &entries))
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "aws:dev:accountId:eu-central-1:cluster1", entries[0].Cluster)
+		assert.Equal(t, "t", entries[0].Config)
+		assert.Equal(t, "testdata/kube.config", entries[0].KubeConfig)
+	}
+}
+
+func Test_listContexts_current(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	orig, err := os.ReadFile("testdata/.state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.WriteFile("testdata/.state", orig, 0600) })
+	// the kube config uses the prod context since a switch outside of ktx
+	stale := []byte("currentKubeConfig: testdata/kube.config\ncurrentContext: aws:dev:accountId:eu-central-1:cluster1\n")
+	if err := os.WriteFile("testdata/.state", stale, 0600); err != nil {
+		t.Fatal(err)
+	}
+	current := func() string {
+		t.Helper()
+		out, err := listContexts([]string{"-o", "json"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries := []k8sctx.InventoryEntry{}
+		if err := json.Unmarshal([]byte(out), &entries); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if e.Current {
+				return e.Name
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "aws:prod:accountId:us-east-1:cluster1", current(), "changed outside of ktx")
+
+	c, err := peekConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kcnf, ctx, err := c.LookupContext("aws:prod:accountId:us-east-1:cluster1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, remove, err := c.Isolate(kcnf, ctx.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { remove() })
+	t.Setenv("KUBECONFIG", path)
+	t.Setenv(contextEnv, "aws:prod:accountId:us-east-1:cluster1")
+	assert.Equal(t, "aws:prod:accountId:us-east-1:cluster1", current(), "shell of ktx shell")
+}
+
+func Test_formatInventory(t *testing.T) {
+	entries := []k8sctx.InventoryEntry{
+		{
+			Name: "prod", Context: "arn:prod", Config: "live", KubeConfig: "/kube/live", Cluster: "prod",
+			Server: "https://prod.example.com", User: "admin", Namespace: "web", Current: true, Protected: true,
+		},
+		{Name: "dev", Context: "dev", Config: "live", KubeConfig: "/kube/live", Cluster: "dev", User: "dev", Previous: true},
+	}
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{output: "name", want: "prod\ndev"},
+		{
+			output: "table",
+			want: "CURRENT  NAME  CLUSTER  USER   NAMESPACE\n" +
+				"*        prod  prod     admin  web\n" +
+				"-        dev   dev      dev    ",
+		},
+		{
+			output: "wide",
+			want: "CURRENT  NAME  CLUSTER  USER   NAMESPACE  CONFIG  SERVER                    KUBECONFIG  PROTECTED\n" +
+				"*        prod  prod     admin  web        live    https://prod.example.com  /kube/live  true\n" +
+				"-        dev   dev      dev               live                              /kube/live  false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			got, err := formatInventory(entries, tt.output)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	t.Run("yaml", func(t *testing.T) {
+		out, err := formatInventory(entries, "yaml")
+		assert.NoError(t, err)
+		got := []k8sctx.InventoryEntry{}
+		assert.NoError(t, yaml.Unmarshal([]byte(out), &got))
+		assert.Equal(t, entries, got)
+	})
+}
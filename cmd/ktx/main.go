@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -47,6 +48,14 @@ var (
 				Foreground(lipgloss.AdaptiveColor{Light: "#ff0000", Dark: "#ff0000"}).
 				Render
 
+	warningMessageStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "#d78700", Dark: "#ffaf00"}).
+				Render
+
+	infoMessageStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#C2B8C2"}).
+				Render
+
 	noContextFound = "No previous context found in state file. You need to switch the kube context at least twice."
 
 	//go:embed jsonnet/.libsonnet
@@ -66,30 +75,41 @@ type model struct {
 	delegateKeys     *delegateKeyMap
 	quitting         bool
 	useInitialFilter bool
+	// switched is the item, whose context was chosen in the list.
+	switched *item
 }
 
 type item struct {
 	title       string
 	description string
+	// config is the alias of the kube config and context the name of the
+	// context inside of it.
+	config  string
+	context string
+	// namespace is set together with the context, if not empty.
+	namespace string
+	// protected items need a second key press to switch.
+	protected bool
+	// fields of the context as strings for the structured filter.
+	fields map[string]string
 }
 
 func (i item) Title() string       { return i.title }
 func (i item) Description() string { return i.description }
-func (i item) FilterValue() string { return i.title }
+func (i item) FilterValue() string { return i.title + "\n" + i.description + "\n" + i.context }
 
 type delegateKeyMap struct {
 	choose key.Binding
 }
 
-func newItemDelegate(keys *delegateKeyMap, c *k8sctx.Config) (list.DefaultDelegate, tea.Cmd) {
+func newItemDelegate(keys *delegateKeyMap, c *k8sctx.Config, switched *item) (list.DefaultDelegate, tea.Cmd) {
 	d := list.NewDefaultDelegate()
+	// pending is the title of the protected item, which was chosen once
+	pending := ""
 
 	d.UpdateFunc = func(msg tea.Msg, m *list.Model) tea.Cmd {
-		var title string
-
-		if i, ok := m.SelectedItem().(item); ok {
-			title = i.Title()
-		} else {
+		i, ok := m.SelectedItem().(item)
+		if !ok {
 			return nil
 		}
 		m.StatusMessageLifetime = 10 * time.Second
@@ -97,30 +117,31 @@ func newItemDelegate(keys *delegateKeyMap, c *k8sctx.Config) (list.DefaultDelega
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			if key.Matches(msg, keys.choose) {
-				kcnf, ctx, idx := c.GetContextBy(title)
-				if idx == -1 {
+				kcnf := c.GetKubeConfigByAlias(i.config)
+				if kcnf == nil || !kcnf.Exists(i.context) {
 					return m.NewStatusMessage(
-						errorMessageStyle(fmt.Sprintf("Context '%s' not found in kube config files", title)),
+						errorMessageStyle(fmt.Sprintf("Context '%s' not found in kube config files", i.Title())),
 					)
 				}
-				if err := c.RemoveCurrentContexts(); err != nil {
+				if i.protected && !assumeYes && pending != i.title {
+					pending = i.title
 					return m.NewStatusMessage(
-						errorMessageStyle(
-							fmt.Sprintf("Failed to remove old current-contexts: '%s'", err.Error())),
+						warningMessageStyle(fmt.Sprintf("Context '%s' is protected. Press %s again to switch.",
+							i.Title(), keys.choose.Help().Key)),
 					)
 				}
-				if err := kcnf.KubeConfig.SetContextTo(ctx["name"]); err != nil {
-					return m.NewStatusMessage(
-						errorMessageStyle(
-							fmt.Sprintf("Failed to set current-context to '%s': '%s'", title, err.Error())),
-					)
+				namespace := i.namespace
+				if _, ns := splitFilter(m.FilterValue(), titles(m.Items())); ns != "" {
+					namespace = ns
 				}
-				if err := c.UpdateState(kcnf, ctx["name"]); err != nil {
+				sw, _ := switcherFor(c)
+				if err := sw.SwitchToNamespace(kcnf, i.context, namespace); err != nil {
 					return m.NewStatusMessage(
 						errorMessageStyle(
-							fmt.Sprintf("Failed to update state file'%s': '%s'", c.Filename, err.Error())),
+							fmt.Sprintf("Failed to set current-context to '%s': '%s'", i.Title(), err.Error())),
 					)
 				}
+				*switched = i
 				return tea.Quit
 			}
 		}
@@ -176,31 +197,50 @@ func newDelegateKeyMap() *delegateKeyMap {
 	}
 }
 
-func modelFrom(c *k8sctx.Config, configFilter, contextFilter string) model {
-	var (
-		delegateKeys = newDelegateKeyMap()
-		listKeys     = newListKeyMap()
-	)
-	contexts := c.CreateListItems(configFilter, contextFilter)
+// describe returns the description of the context item, which starts with a
+// warning, if the namespace of the context drifted.
+func describe(ctx k8sctx.ContextItem) string {
+	if ctx.Drift == nil {
+		return ctx.Description
+	}
+	return strings.TrimSuffix(fmt.Sprintf("⚠ namespace drift (kubeconfig: %s, config: %s), %s",
+		orNone(ctx.Drift.KubeConfig), orNone(ctx.Drift.Config), ctx.Description), ", ")
+}
+
+func modelFrom(c *k8sctx.Config, contexts []k8sctx.ContextItem, useInitialFilter bool) model {
 	items := make([]list.Item, len(contexts))
 	for idx, ctx := range contexts {
 		i := item{
 			title:       ctx.Name,
-			description: ctx.Description,
+			description: describe(ctx),
+			config:      ctx.Config,
+			context:     ctx.Context,
+			protected:   ctx.Protected,
+			fields:      ctx.Fields,
 		}
 		items[idx] = i
 	}
+	return listModel(c, "Kube Contexts", items, useInitialFilter)
+}
 
-	// Setup list
-	delegate, _ := newItemDelegate(delegateKeys, c)
+// listModel returns the model of a list of contexts. Choosing an item
+// switches to its context.
+func listModel(c *k8sctx.Config, title string, items []list.Item, useInitialFilter bool) model {
+	var (
+		delegateKeys = newDelegateKeyMap()
+		listKeys     = newListKeyMap()
+	)
+	switched := &item{}
+	delegate, _ := newItemDelegate(delegateKeys, c, switched)
 	delegate.Styles.DimmedTitle = dimmedTitle
 	delegate.Styles.DimmedDesc = dimmedDesc
-	contextList := list.New(items, delegate, 0, 0)
+	contextList := list.New(items, newContextDelegate(delegate), 0, 0)
 	contextList.Styles.StatusBarFilterCount = statusBarFilterCount
 	contextList.Styles.StatusBar = statusBar
-	contextList.Title = "Kube Contexts"
+	contextList.Title = title
 	contextList.Styles.Title = titleStyle
 	contextList.FilterInput.Prompt = `Filter: `
+	contextList.Filter = newFilter(items)
 	contextList.AdditionalFullHelpKeys = func() []key.Binding {
 		return []key.Binding{
 			listKeys.toggleHelpMenu,
@@ -212,10 +252,34 @@ func modelFrom(c *k8sctx.Config, configFilter, contextFilter string) model {
 		keys:             listKeys,
 		delegateKeys:     delegateKeys,
 		contexts:         c,
-		useInitialFilter: contextFilter == "",
+		useInitialFilter: useInitialFilter,
+		switched:         switched,
 	}
 }
 
+// splitFilter splits the namespace from a filter term like "lab/monitoring".
+// The term is only split, if the whole term matches none of the targets, so
+// that qualified names like "dev/lab" can still be filtered.
+func splitFilter(term string, targets []string) (string, string) {
+	idx := strings.LastIndex(term, "/")
+	if idx <= 0 || len(list.DefaultFilter(term, targets)) > 0 {
+		return term, ""
+	}
+	return term[:idx], term[idx+1:]
+}
+
+// titles returns the titles of the items.
+func titles(items []list.Item) []string {
+	values := make([]string, len(items))
+	for idx, li := range items {
+		values[idx] = li.FilterValue()
+		if i, ok := li.(item); ok {
+			values[idx] = i.title
+		}
+	}
+	return values
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
@@ -303,7 +367,7 @@ func getConfigDir(testing string) (string, error) {
 	}
 	if testing == "" {
 		// Create the config directory if it doesn't exist
-		err := os.MkdirAll(configDir, 0755)
+		err := os.MkdirAll(configDir, 0700)
 		if err != nil {
 			return "", err
 		}
@@ -359,7 +423,9 @@ func initConfigFile(testing string) (string, error) {
 	return tpl.String(), nil
 }
 
-func loadConfigs() (*k8sctx.Config, error) {
+// readConfigs creates the config files, if they don't exist, and reads them
+// without syncing the kube configs and contexts files.
+func readConfigs() (*k8sctx.Config, error) {
 	configDir, err := getConfigDir("")
 	if err != nil {
 		return nil, err
@@ -370,41 +436,148 @@ func loadConfigs() (*k8sctx.Config, error) {
 		if err != nil {
 			return nil, err
 		}
-		if err := os.WriteFile(configFile, []byte(cnf), 0644); err != nil {
+		if err := os.WriteFile(configFile, []byte(cnf), k8sctx.FileMode); err != nil {
 			return nil, err
 		}
 	}
 	contextsFile := filepath.Join(configDir, ".libsonnet")
 	if !fileExists(contextsFile) {
-		if err := os.WriteFile(contextsFile, []byte(contextsLibsonnet), 0644); err != nil {
+		if err := os.WriteFile(contextsFile, []byte(contextsLibsonnet), k8sctx.FileMode); err != nil {
 			return nil, err
 		}
 	}
+	return k8sctx.Get(configFile)
+}
 
-	c, err := k8sctx.Get(configFile)
+// loadConfigs reads the config files and syncs the kube configs and contexts
+// files, if one of them changed since the last sync.
+func loadConfigs() (*k8sctx.Config, error) {
+	c, err := readConfigs()
 	if err != nil {
 		return nil, err
 	}
-	if err := c.SyncNamespaces(); err != nil {
+	if !c.Synced() {
+		reports, err := syncLocked(c, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := reloadAfter(c, reports); err != nil {
+			return nil, err
+		}
+		for _, r := range reports {
+			if out := r.String(); out != "" {
+				fmt.Fprintln(os.Stderr, infoMessageStyle(out))
+			}
+		}
+	}
+	c.Terminal = k8sctx.TerminalID()
+	if err := reconcileState(c); err != nil {
 		return nil, err
 	}
-	reload := false
-	for _, kcnf := range c.KubeConfs {
-		if !fileExists(kcnf.ContextFile) {
-			reload = true
-		}
-		if err := kcnf.KubeConfig.SyncContexts(kcnf); err != nil {
+	printWarnings(c)
+	return c, nil
+}
+
+// peekConfigs reads the config files like loadConfigs, but neither syncs them
+// nor reconciles the .state file: read-only commands and the ones, which only
+// start child processes, must not change any file. The contexts are synced
+// only in memory and a current context changed outside of ktx is only warned
+// about.
+func peekConfigs() (*k8sctx.Config, error) {
+	c, err := readConfigs()
+	if err != nil {
+		return nil, err
+	}
+	c.Terminal = k8sctx.TerminalID()
+	if !c.Synced() {
+		if err := c.SyncInMemory(); err != nil {
 			return nil, fmt.Errorf("sync contexts: %w", err)
 		}
 	}
+	if err := checkState(c); err != nil {
+		return nil, err
+	}
+	printWarnings(c)
+	return c, nil
+}
 
-	if reload {
-		c, err = k8sctx.Get(configFile)
-		if err != nil {
-			return nil, fmt.Errorf("read contexts file: %w", err)
+// reconcileState records a current context, which was changed outside of
+// ktx, into the .state file and adds a warning about it. Sessions and
+// isolated shells have their own kube configs and are skipped.
+func reconcileState(c *k8sctx.Config) error {
+	if activeSession(c) != nil || isolatedContext(c) != "" {
+		return nil
+	}
+	m, err := c.ReconcileState(kubeConfigsWithout(c))
+	if err != nil {
+		return fmt.Errorf("reconcile state file: %w", err)
+	}
+	if m != nil {
+		c.Warnings = append(c.Warnings, errors.New(m.String()))
+	}
+	return nil
+}
+
+// checkState adds a warning, if the current context was changed outside of
+// ktx, like reconcileState, but leaves the .state file as it is.
+func checkState(c *k8sctx.Config) error {
+	if activeSession(c) != nil || isolatedContext(c) != "" {
+		return nil
+	}
+	m, err := c.CheckState(kubeConfigsWithout(c))
+	if err != nil {
+		return fmt.Errorf("read state file: %w", err)
+	}
+	if m != nil {
+		c.Warnings = append(c.Warnings, errors.New(m.String()))
+	}
+	return nil
+}
+
+// syncLocked reads the config files again and syncs them, while it holds
+// the lock of the config dir. With changedOnly, the sync is skipped, if
+// another ktx process synced the files in the meantime.
+func syncLocked(c *k8sctx.Config, changedOnly bool) ([]k8sctx.SyncReport, error) {
+	unlock, err := c.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	if changedOnly && c.Synced() {
+		return nil, nil
+	}
+	return c.Sync(false)
+}
+
+// reloadAfter reads the config files again, if the sync changed one of them,
+// and stores the fingerprint of the synced files.
+func reloadAfter(c *k8sctx.Config, reports []k8sctx.SyncReport) error {
+	unlock, err := c.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, r := range reports {
+		if r.Changed() {
+			if err := c.Reload(); err != nil {
+				return fmt.Errorf("read contexts file: %w", err)
+			}
+			break
 		}
 	}
-	return c, nil
+	if err := c.SaveFingerprint(); err != nil {
+		return fmt.Errorf("save sync fingerprint: %w", err)
+	}
+	return nil
+}
+
+func printWarnings(c *k8sctx.Config) {
+	for _, w := range c.Warnings {
+		fmt.Fprintln(os.Stderr, warningMessageStyle("warning: "+w.Error()))
+	}
 }
 
 func run(filters []string) (string, error) {
@@ -412,6 +585,28 @@ func run(filters []string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	contexts, useInitialFilter, err := listItems(c, filters)
+	if err != nil {
+		return "", err
+	}
+	m := modelFrom(c, contexts, useInitialFilter)
+	if _, err := newProgram(m).Run(); err != nil {
+		return "", fmt.Errorf("error running program: %w", err)
+	}
+	printBanner(c.GetKubeConfigByAlias(m.switched.config), m.switched.context)
+	return getCurrentContext()
+}
+
+// listItems returns the contexts for the TUI. The filters are either the
+// alias of a kube config followed by a part of the context name or a
+// selector (see k8sctx.Config.Select), like "environment in (prod,stage)".
+func listItems(c *k8sctx.Config, filters []string) ([]k8sctx.ContextItem, bool, error) {
+	for _, f := range filters {
+		if k8sctx.IsSelector(f) {
+			contexts, err := c.SelectListItems(filters)
+			return contexts, true, err
+		}
+	}
 	configFilter := ""
 	contextFilter := ""
 
@@ -421,11 +616,17 @@ func run(filters []string) (string, error) {
 	case 2:
 		configFilter, contextFilter = filters[0], filters[1]
 	}
+	return c.CreateListItems(configFilter, contextFilter), contextFilter == "", nil
+}
 
-	if _, err := tea.NewProgram(modelFrom(c, configFilter, contextFilter), tea.WithAltScreen()).Run(); err != nil {
-		return "", fmt.Errorf("error running program: %w", err)
+// newProgram returns the TUI program for the model. It renders to stderr, if
+// the stdout is read by the shell function.
+func newProgram(m tea.Model) *tea.Program {
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if wrapped() {
+		opts = append(opts, tea.WithOutput(os.Stderr))
 	}
-	return getCurrentContext()
+	return tea.NewProgram(m, opts...)
 }
 
 func directlyUse(context string) (string, error) {
@@ -434,67 +635,94 @@ func directlyUse(context string) (string, error) {
 		return "", err
 	}
 
-	kcnf, ctx, idx := c.GetContextBy(context)
-	if idx == -1 {
-		return "", fmt.Errorf("context '%s' not found in kube config files", context)
+	kcnf, ctx, namespace, err := lookupOrSelect(c, context)
+	if err != nil {
+		return "", err
 	}
-	if err := c.RemoveCurrentContexts(); err != nil {
-		return "", fmt.Errorf("failed to remove old current-contexts: %w", err)
+	if k8sctx.IsSelector(context) {
+		context = ctx.Name
 	}
-	if err := kcnf.KubeConfig.SetContextTo(ctx["name"]); err != nil {
-		return "", fmt.Errorf("failed to set current-context to '%s': %w", context, err)
+	if err := confirmProtected(kcnf, ctx.Name); err != nil {
+		return "", err
 	}
-	if err := c.UpdateState(kcnf, ctx["name"]); err != nil {
-		return "", fmt.Errorf("failed to update state file '%s': %w", c.Filename, err)
+	sw, _ := switcherFor(c)
+	if err := sw.SwitchToNamespace(kcnf, ctx.Name, namespace); err != nil {
+		return "", fmt.Errorf("failed to set current-context to '%s': %w", context, err)
 	}
+	printBanner(kcnf, ctx.Name)
 	return context, nil
 }
 
+// lookupOrSelect returns the context of the name or, for a selector like
+// "environment=prod,region=eu", the only context matching the selector.
+func lookupOrSelect(c *k8sctx.Config, context string) (*k8sctx.KubeConf, *k8sctx.ContextEntry, string, error) {
+	if !k8sctx.IsSelector(context) {
+		return c.LookupContextNamespace(context)
+	}
+	s, err := c.SelectOne([]string{context})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return s.KubeConf, s.Context, "", nil
+}
+
 func switchBack() (string, error) {
 	c, err := loadConfigs()
 	if err != nil {
 		return "", err
 	}
-	if err := c.GetState(); err != nil {
+	sw, state := switcherFor(c)
+	if err := sw.GetState(); err != nil {
 		return "", err
 	}
-	kcnf := c.GetKubeConfigBy(c.LastConf)
+	last := state.Previous(c.Terminal)
+	kcnf := c.GetKubeConfigBy(last.KubeConfig)
 	if kcnf == nil {
 		return noContextFound, nil
 	}
-	lastContext := c.LastContext
-
-	if err := c.RemoveCurrentContexts(); err != nil {
+	if err := confirmProtected(kcnf, last.Context); err != nil {
 		return "", err
 	}
 
-	if err := kcnf.KubeConfig.SetContextTo(lastContext); err != nil {
-		return "", fmt.Errorf("using previous context failed while setting context: %w", err)
+	if err := sw.SwitchToNamespace(kcnf, last.Context, last.Namespace); err != nil {
+		return "", fmt.Errorf("using previous context failed: %w", err)
 	}
-	if err := c.UpdateState(kcnf, lastContext); err != nil {
-		return "", fmt.Errorf("using previous context failed while updating state file: %w", err)
-	}
-	return lastContext, nil
+	printBanner(kcnf, last.Context)
+	return last.Context, nil
 }
 
 func getCurrentContext() (string, error) {
-	c, err := loadConfigs()
+	c, err := peekConfigs()
 	if err != nil {
 		return "", err
 	}
 
-	if err := c.GetState(); err != nil {
+	if ctx := isolatedContext(c); ctx != "" {
+		return ctx, nil
+	}
+	if activeSession(c) == nil {
+		// a switch outside of ktx is not yet recorded in the .state file
+		if k, name := c.ActualContext(kubeConfigsWithout(c)); k != nil {
+			return name, nil
+		}
+	}
+	sw, state := switcherFor(c)
+	if err := sw.GetState(); err != nil {
 		return "", fmt.Errorf("getting current context failed while reading state file: %w", err)
 	}
-	kcnf := c.GetKubeConfigBy(c.CurrentConf)
+	kcnf := c.GetKubeConfigBy(state.CurrentConf)
 	if kcnf == nil {
 		return noContextFound, nil
 	}
-	return c.CurrentContext, nil
+	return state.CurrentContext, nil
 }
 
 func runWith(args []string) (string, error) {
+	args, assumeYes = withoutYes(args)
 	if len(args) > 1 {
+		if n, ok := steps(args[1]); ok {
+			return jumpBack(n)
+		}
 		switch args[1] {
 		case "-h", "-help":
 			return helpText, nil
@@ -505,8 +733,34 @@ func runWith(args []string) (string, error) {
 				return directlyUse(args[2])
 			}
 			return getCurrentContext()
+		case "-s", "-session":
+			if len(args) > 2 {
+				return useSession(args[2])
+			}
+			return endSession()
 		case "-":
 			return switchBack()
+		case "-complete":
+			return complete(args[2:])
+		case "init":
+			return initShell(args[2:])
+		case "shell":
+			if len(args) < 3 {
+				return "", errMissingContext
+			}
+			return startShell(args[2])
+		case "exec":
+			return execIn(args[2:])
+		case "each":
+			return runEach(args[2:])
+		case "sync":
+			return syncConfigs(args[2:])
+		case "ns":
+			return switchNamespace(args[2:])
+		case "history":
+			return showHistory(args[2:])
+		case "list":
+			return listContexts(args[2:])
 		}
 	}
 	return run(args[1:])
@@ -514,8 +768,14 @@ func runWith(args []string) (string, error) {
 
 func main() {
 	msg, err := runWith(os.Args)
+	var exit *exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
 	if err != nil {
 		log.Fatalln(err)
 	}
-	fmt.Println(msg)
+	if msg != "" {
+		fmt.Println(msg)
+	}
 }
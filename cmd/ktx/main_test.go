@@ -2,6 +2,7 @@ package main
 
 import (
 	_ "embed"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -68,6 +69,23 @@ var (
 	}
 )
 
+func TestMain(m *testing.M) {
+	state, err := os.ReadFile("testdata/.state")
+	if err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	// written by every sync of the config in testdata
+	os.Remove("testdata/.sync")
+	// created by every change of the files in testdata
+	os.Remove("testdata/.lock")
+	// the switches add timestamps to the history
+	if err := os.WriteFile("testdata/.state", state, 0600); err != nil {
+		panic(err)
+	}
+	os.Exit(code)
+}
+
 func Test_getConfigDir(t *testing.T) {
 
 	currDir, err := os.Getwd()
@@ -168,22 +186,28 @@ func Test_loadConfigs(t *testing.T) {
 						Alias:       "t",
 						ContextFile: "testdata/contexts_t.yaml",
 						KubeConfig:  kCnf,
-						Contexts: []map[string]string{
+						Contexts: []*k8sctx.ContextEntry{
 							{
-								"kubeconfig": "testdata/kube.config",
-								"name":       "aws:dev:accountId:eu-central-1:cluster1",
-								"index":      "0",
+								KubeConfig: "testdata/kube.config",
+								Name:       "aws:dev:accountId:eu-central-1:cluster1",
+								Metadata: map[string]any{
+									"index": "0",
+								},
 							},
 							{
-								"kubeconfig": "testdata/kube.config",
-								"name":       "aws:prod:accountId:us-east-1:cluster1",
-								"index":      "1",
+								KubeConfig: "testdata/kube.config",
+								Name:       "aws:prod:accountId:us-east-1:cluster1",
+								Metadata: map[string]any{
+									"index": "1",
+								},
 							},
 						},
 					},
 				},
 				State: &k8sctx.State{
-					Filename: "testdata/.state",
+					Filename:       "testdata/.state",
+					CurrentConf:    "testdata/kube.config",
+					CurrentContext: "aws:prod:accountId:us-east-1:cluster1",
 				},
 			},
 			wantErr:     false,
@@ -207,10 +231,33 @@ func Test_loadConfigs(t *testing.T) {
 				return
 			}
 
-			assert.Equal(t, tt.want, got)
-
 			t.Cleanup(func() { os.Unsetenv(tt.setEnv) })
 			t.Cleanup(func() { os.RemoveAll("testdata/contexts_t.yaml") })
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want.Dir, got.Dir)
+			assert.Equal(t, tt.want.GlobalConfig, got.GlobalConfig)
+			// the state is reconciled with the current context of the kube config
+			assert.Equal(t, tt.want.State.Filename, got.State.Filename)
+			assert.Equal(t, tt.want.State.CurrentConf, got.State.CurrentConf)
+			assert.Equal(t, tt.want.State.CurrentContext, got.State.CurrentContext)
+			if !assert.Len(t, got.KubeConfs, len(tt.want.KubeConfs)) {
+				return
+			}
+			for idx, g := range got.KubeConfs {
+				w := tt.want.KubeConfs[idx]
+				assert.Equal(t, w.Path, g.Path)
+				assert.Equal(t, w.Alias, g.Alias)
+				assert.Equal(t, w.ContextFile, g.ContextFile)
+				assert.Equal(t, w.Contexts, g.Contexts)
+				assert.Equal(t, w.KubeConfig.Path, g.KubeConfig.Path)
+				assert.Equal(t, w.KubeConfig.Contexts, g.KubeConfig.Contexts)
+				assert.Equal(t, w.KubeConfig.Clusters, g.KubeConfig.Clusters)
+				assert.Equal(t, w.KubeConfig.Users, g.KubeConfig.Users)
+				assert.Equal(t, w.KubeConfig.CurrentContext, g.KubeConfig.CurrentContext)
+			}
 		})
 	}
 }
@@ -247,6 +294,68 @@ func Test_getCurrentContext(t *testing.T) {
 	}
 }
 
+func Test_peekConfigs(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	orig, err := os.ReadFile("testdata/.state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.WriteFile("testdata/.state", orig, 0600) })
+	// a switch to the prod context outside of ktx
+	stale := []byte("currentKubeConfig: testdata/kube.config\ncurrentContext: aws:dev:accountId:eu-central-1:cluster1\n")
+	if err := os.WriteFile("testdata/.state", stale, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the contexts of the kube config are new
+	if contexts, err := os.ReadFile("testdata/contexts_t.yaml"); err == nil {
+		t.Cleanup(func() { os.WriteFile("testdata/contexts_t.yaml", contexts, 0600) })
+	}
+	os.Remove("testdata/contexts_t.yaml")
+	os.Remove("testdata/.sync")
+
+	completed, err := complete([]string{"-contexts"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:dev:accountId:eu-central-1:cluster1\naws:prod:accountId:us-east-1:cluster1", completed)
+	listed, err := listContexts([]string{"-o", "name"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:dev:accountId:eu-central-1:cluster1\naws:prod:accountId:us-east-1:cluster1", listed)
+	var got string
+	warnings := captureStderr(t, func() {
+		got, err = getCurrentContext()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:prod:accountId:us-east-1:cluster1", got)
+	assert.Contains(t, warnings, "the .state file says the current context is "+
+		"'aws:dev:accountId:eu-central-1:cluster1', but the kube configs use 'aws:prod:accountId:us-east-1:cluster1'")
+
+	state, err := os.ReadFile("testdata/.state")
+	assert.NoError(t, err)
+	assert.Equal(t, string(stale), string(state), "state file was reconciled")
+	assert.NoFileExists(t, "testdata/.sync", "config files were synced")
+	assert.NoFileExists(t, "testdata/contexts_t.yaml", "contexts file was written")
+}
+
+// captureStderr returns, what fn writes to stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
 func Test_switchBack(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -299,6 +408,22 @@ func Test_directlyUse(t *testing.T) {
 			setEnv:      "KTX_CONFIG_DIR",
 			setEnvValue: "testdata",
 		},
+		{
+			name:        "positive - selector",
+			args:        args{context: "name=aws:prod:*,kubeconfig=testdata/*"},
+			want:        "aws:prod:accountId:us-east-1:cluster1",
+			wantErr:     false,
+			setEnv:      "KTX_CONFIG_DIR",
+			setEnvValue: "testdata",
+		},
+		{
+			name:        "negative - selector matches more than one context",
+			args:        args{context: "name!=minikube"},
+			want:        "",
+			wantErr:     true,
+			setEnv:      "KTX_CONFIG_DIR",
+			setEnvValue: "testdata",
+		},
 		{
 			name:        "negative - context no found",
 			args:        args{context: "does not exists"},
@@ -323,3 +448,71 @@ func Test_directlyUse(t *testing.T) {
 		})
 	}
 }
+
+func Test_splitFilter(t *testing.T) {
+	targets := []string{"lab", "dev/minikube", "lab/minikube"}
+	tests := []struct {
+		name          string
+		term          string
+		wantTerm      string
+		wantNamespace string
+	}{
+		{name: "without namespace", term: "lab", wantTerm: "lab"},
+		{name: "namespace", term: "lab/monitoring", wantTerm: "lab", wantNamespace: "monitoring"},
+		{name: "qualified name", term: "dev/mini", wantTerm: "dev/mini"},
+		{name: "qualified name with namespace", term: "dev/minikube/web", wantTerm: "dev/minikube", wantNamespace: "web"},
+		{name: "unknown namespace", term: "lab/x", wantTerm: "lab", wantNamespace: "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term, ns := splitFilter(tt.term, targets)
+			assert.Equal(t, tt.wantTerm, term)
+			assert.Equal(t, tt.wantNamespace, ns)
+		})
+	}
+}
+
+func Test_listItems(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	c, err := loadConfigs()
+	if !assert.NoError(t, err) {
+		return
+	}
+	tests := []struct {
+		name                 string
+		filters              []string
+		want                 []string
+		wantUseInitialFilter bool
+		wantErr              error
+	}{
+		{
+			name:                 "all",
+			want:                 []string{"aws:dev:accountId:eu-central-1:cluster1", "aws:prod:accountId:us-east-1:cluster1"},
+			wantUseInitialFilter: true,
+		},
+		{name: "context filter", filters: []string{"t", "prod"}, want: []string{"aws:prod:accountId:us-east-1:cluster1"}},
+		{
+			name:                 "selector",
+			filters:              []string{"name notin (aws:prod:*)"},
+			want:                 []string{"aws:dev:accountId:eu-central-1:cluster1"},
+			wantUseInitialFilter: true,
+		},
+		{name: "negative - invalid selector", filters: []string{"name in (aws"}, wantErr: k8sctx.ErrSelector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contexts, useInitialFilter, err := listItems(c, tt.filters)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			names := []string{}
+			for _, ctx := range contexts {
+				names = append(names, ctx.Name)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, tt.wantUseInitialFilter, useInitialFilter)
+		})
+	}
+}
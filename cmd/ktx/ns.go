@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/peterbueschel/k8sctx"
+)
+
+// namespaceTimeout limits the time to read the namespaces from the cluster.
+const namespaceTimeout = 5 * time.Second
+
+// nsTarget is the current context, whose namespace is changed by "ktx ns".
+type nsTarget struct {
+	// config keeps the input files synced after the change (see
+	// k8sctx.Config.KeepSynced).
+	config *k8sctx.Config
+	// kubeConf holds the context with its credentials and the namespaces
+	// declared in the contexts file.
+	kubeConf *k8sctx.KubeConf
+	// context is the name of the context inside the kube config.
+	context string
+	// file is the session or isolated kube config of the shell, which gets
+	// the namespace instead of the kube config of kubeConf. It is nil outside
+	// of sessions and isolated shells.
+	file *k8sctx.KubeConfig
+}
+
+// currentTarget returns the current context of the shell. In an isolated
+// shell or a session, only the kube config of the shell is changed.
+func currentTarget(c *k8sctx.Config) (*nsTarget, error) {
+	if name := isolatedContext(c); name != "" {
+		file, err := k8sctx.GetKubeConfig(filepath.SplitList(os.Getenv("KUBECONFIG"))[0])
+		if err != nil {
+			return nil, err
+		}
+		kcnf, ctx, err := c.LookupContext(name)
+		if err != nil {
+			return nil, err
+		}
+		return &nsTarget{config: c, kubeConf: kcnf, context: ctx.Name, file: file}, nil
+	}
+	t := &nsTarget{config: c}
+	sw, state := switcherFor(c)
+	if err := sw.GetState(); err != nil {
+		return nil, fmt.Errorf("getting current context failed while reading state file: %w", err)
+	}
+	if t.kubeConf = c.GetKubeConfigBy(state.CurrentConf); t.kubeConf == nil {
+		return nil, fmt.Errorf("%w: %s", k8sctx.ErrNoContext, noContextFound)
+	}
+	t.context = state.CurrentContext
+	if s, ok := sw.(*k8sctx.Session); ok {
+		file, err := k8sctx.GetKubeConfig(s.KubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		t.file = file
+	}
+	return t, nil
+}
+
+// namespace returns the current namespace of the context.
+func (t *nsTarget) namespace() string {
+	k := t.file
+	if k == nil {
+		k = t.kubeConf.KubeConfig
+	}
+	if ctx, _, err := k.GetContextBy(t.context); err == nil {
+		return ctx.Namespace
+	}
+	return ""
+}
+
+// set writes the namespace into the kube config of the shell or into the
+// kube config and contexts file of the context.
+func (t *nsTarget) set(namespace string) error {
+	if t.file != nil {
+		return t.file.AddNamespaceTo(t.context, namespace)
+	}
+	return t.config.KeepSynced(func() (bool, error) {
+		return true, t.kubeConf.SetNamespace(t.context, namespace)
+	})
+}
+
+// switchNamespace sets the namespace of the current context. Without a
+// namespace as argument, the namespaces are shown in a list to choose from.
+func switchNamespace(args []string) (string, error) {
+	c, err := loadConfigs()
+	if err != nil {
+		return "", err
+	}
+	t, err := currentTarget(c)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), namespaceTimeout)
+	defer cancel()
+
+	if len(args) > 0 {
+		namespace := args[0]
+		names, fromCluster, err := t.kubeConf.Namespaces(ctx, t.context)
+		if err == nil && fromCluster && !slices.Contains(names, namespace) {
+			return "", fmt.Errorf("namespace '%s' not found in context '%s'", namespace, t.context)
+		}
+		if err := t.set(namespace); err != nil {
+			return "", fmt.Errorf("failed to set namespace '%s': %w", namespace, err)
+		}
+		return namespace, nil
+	}
+
+	names, fromCluster, err := t.kubeConf.Namespaces(ctx, t.context)
+	if err != nil {
+		return "", err
+	}
+	if _, err := newProgram(nsModelFrom(t, names, fromCluster)).Run(); err != nil {
+		return "", fmt.Errorf("error running program: %w", err)
+	}
+	return t.namespace(), nil
+}
+
+// nsModelFrom returns the list of the namespaces with the current one
+// selected.
+func nsModelFrom(t *nsTarget, names []string, fromCluster bool) model {
+	delegateKeys := newDelegateKeyMap()
+	listKeys := newListKeyMap()
+	current := t.namespace()
+	items := make([]list.Item, len(names))
+	selected := 0
+	for idx, name := range names {
+		i := item{title: name, context: t.context}
+		if name == current {
+			i.description = "current"
+			selected = idx
+		}
+		items[idx] = i
+	}
+
+	delegate := newNamespaceDelegate(delegateKeys, t)
+	delegate.Styles.DimmedTitle = dimmedTitle
+	delegate.Styles.DimmedDesc = dimmedDesc
+	nsList := list.New(items, delegate, 0, 0)
+	nsList.Styles.StatusBarFilterCount = statusBarFilterCount
+	nsList.Styles.StatusBar = statusBar
+	nsList.Title = fmt.Sprintf("Namespaces of %s", t.context)
+	if !fromCluster {
+		nsList.Title += " (from contexts file)"
+	}
+	nsList.Styles.Title = titleStyle
+	nsList.FilterInput.Prompt = `Filter: `
+	nsList.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			listKeys.toggleHelpMenu,
+		}
+	}
+	nsList.Select(selected)
+
+	return model{
+		list:         nsList,
+		keys:         listKeys,
+		delegateKeys: delegateKeys,
+	}
+}
+
+func newNamespaceDelegate(keys *delegateKeyMap, t *nsTarget) list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+
+	d.UpdateFunc = func(msg tea.Msg, m *list.Model) tea.Cmd {
+		i, ok := m.SelectedItem().(item)
+		if !ok {
+			return nil
+		}
+		m.StatusMessageLifetime = 10 * time.Second
+
+		if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, keys.choose) {
+			if err := t.set(i.title); err != nil {
+				return m.NewStatusMessage(
+					errorMessageStyle(fmt.Sprintf("Failed to set namespace to '%s': '%s'", i.title, err.Error())),
+				)
+			}
+			return tea.Quit
+		}
+		return nil
+	}
+
+	help := []key.Binding{keys.choose}
+	d.ShortHelpFunc = func() []key.Binding {
+		return help
+	}
+	d.FullHelpFunc = func() [][]key.Binding {
+		return [][]key.Binding{help}
+	}
+	return d
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_switchNamespace(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	t.Cleanup(func() { os.RemoveAll("testdata/isolated") })
+	kubeConfig, err := os.ReadFile("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kcnf, ctx, err := c.LookupContext("aws:dev:accountId:eu-central-1:cluster1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, _, err := c.Isolate(kcnf, ctx.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", path)
+	t.Setenv(contextEnv, "aws:dev:accountId:eu-central-1:cluster1")
+
+	// the cluster cannot be reached, so the namespace is not checked
+	got, err := switchNamespace([]string{"web"})
+	if err != nil {
+		t.Fatalf("switchNamespace() error = %v", err)
+	}
+	assert.Equal(t, "web", got)
+
+	isolated, err := k8sctx.GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kctx, _, err := isolated.GetContextBy(ctx.Name)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "web", kctx.Namespace)
+	}
+	unchanged, err := os.ReadFile("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(kubeConfig), string(unchanged))
+}
+
+func Test_nsModelFrom(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	kube := "contexts:\n- name: a\n  context: {cluster: a, user: a, namespace: web}\ncurrent-context: a\n"
+	if err := os.WriteFile(path, []byte(kube), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := k8sctx.GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &nsTarget{
+		config: &k8sctx.Config{},
+		kubeConf: &k8sctx.KubeConf{
+			Path: path, Alias: "x", KubeConfig: k,
+			ContextFile: filepath.Join(dir, "contexts_x.yaml"),
+			Contexts:    []*k8sctx.ContextEntry{{Name: "a"}},
+		},
+		context: "a",
+	}
+
+	m := nsModelFrom(target, []string{"default", "monitoring", "web"}, false)
+	assert.Equal(t, "Namespaces of a (from contexts file)", m.list.Title)
+	assert.Equal(t, 2, m.list.Index(), "current namespace is not selected")
+	assert.Equal(t, "current", m.list.SelectedItem().(item).Description())
+
+	m.list.Select(1)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if assert.NotNil(t, cmd) {
+		assert.Equal(t, tea.Quit(), cmd())
+	}
+	assert.Equal(t, "monitoring", target.namespace())
+	got, err := k8sctx.GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kctx, _, _ := got.GetContextBy("a")
+	assert.Equal(t, "monitoring", kctx.Namespace)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/peterbueschel/k8sctx"
+)
+
+var (
+	// assumeYes skips the confirmation of protected contexts. It is set by
+	// the "--yes" option.
+	assumeYes bool
+
+	protectedBannerStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("#FFFDF5")).
+				Background(lipgloss.Color("#D70000")).
+				Padding(0, 1).
+				Render
+
+	protectedTitle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Light: "#d78700", Dark: "#ffaf00"})
+)
+
+// childCommands are the commands, which run other programs. Their arguments
+// are passed on as they are.
+var childCommands = []string{"shell", "exec", "each"}
+
+// withoutYes removes the "--yes|-yes|-y" options of ktx itself from the
+// arguments and reports, if one was given. The arguments behind "--" and
+// the ones of the childCommands are kept as they are.
+func withoutYes(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	yes := false
+	for idx, arg := range args {
+		switch {
+		case idx > 0 && arg == "--":
+			return append(rest, args[idx:]...), yes
+		case idx > 0 && (arg == "--yes" || arg == "-yes" || arg == "-y"):
+			yes = true
+			continue
+		}
+		rest = append(rest, arg)
+		if len(rest) == 2 && slices.Contains(childCommands, arg) {
+			return append(rest, args[idx+1:]...), yes
+		}
+	}
+	return rest, yes
+}
+
+// confirmProtected asks for a confirmation on the terminal, if the context
+// is protected. Without a terminal, k8sctx.ErrProtected is returned.
+func confirmProtected(kcnf *k8sctx.KubeConf, contextName string) error {
+	if assumeYes || !kcnf.Protected(contextName) {
+		return nil
+	}
+	name := kcnf.DisplayName(contextName)
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("%w: '%s', use --yes to use it anyway", k8sctx.ErrProtected, name)
+	}
+	return confirm(name, os.Stdin, os.Stderr)
+}
+
+// confirmSelected asks once for a confirmation of all protected contexts of
+// the selection. A single protected context is confirmed by its name, more
+// of them by their number.
+func confirmSelected(selected []k8sctx.SelectedContext) error {
+	protected := []k8sctx.SelectedContext{}
+	for _, s := range selected {
+		if s.KubeConf.Protected(s.Context.Name) {
+			protected = append(protected, s)
+		}
+	}
+	switch {
+	case assumeYes || len(protected) == 0:
+		return nil
+	case len(protected) == 1:
+		return confirmProtected(protected[0].KubeConf, protected[0].Context.Name)
+	}
+	names := make([]string, len(protected))
+	for idx, s := range protected {
+		names[idx] = s.QualifiedName()
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("%w: '%s', use --yes to use them anyway", k8sctx.ErrProtected, strings.Join(names, "', '"))
+	}
+	return confirmAll(names, os.Stdin, os.Stderr)
+}
+
+// confirm lets the user type the name of the protected context.
+func confirm(name string, in io.Reader, out io.Writer) error {
+	fmt.Fprint(out, warningMessageStyle(fmt.Sprintf("Context '%s' is protected. Type its name to use it: ", name)))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(answer) != name {
+		return fmt.Errorf("%w: '%s', the typed name doesn't match", k8sctx.ErrProtected, name)
+	}
+	return nil
+}
+
+// confirmAll lets the user type the number of the protected contexts.
+func confirmAll(names []string, in io.Reader, out io.Writer) error {
+	fmt.Fprint(out, warningMessageStyle(fmt.Sprintf("Contexts '%s' are protected. Type their number to use them: ",
+		strings.Join(names, "', '"))))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(answer) != strconv.Itoa(len(names)) {
+		return fmt.Errorf("%w: '%s', the typed number doesn't match", k8sctx.ErrProtected, strings.Join(names, "', '"))
+	}
+	return nil
+}
+
+// printBanner prints a banner to stderr, if the context is protected.
+func printBanner(kcnf *k8sctx.KubeConf, contextName string) {
+	if kcnf != nil && kcnf.Protected(contextName) {
+		fmt.Fprintln(os.Stderr, protectedBannerStyle("⚠ PROTECTED CONTEXT: "+kcnf.DisplayName(contextName)))
+	}
+}
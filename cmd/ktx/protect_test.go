@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_withoutYes(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantArgs []string
+		wantYes  bool
+	}{
+		{
+			name:     "positive - without option",
+			args:     []string{"ktx", "-c", "prod"},
+			wantArgs: []string{"ktx", "-c", "prod"},
+		},
+		{
+			name:     "positive - long option",
+			args:     []string{"ktx", "--yes", "-c", "prod"},
+			wantArgs: []string{"ktx", "-c", "prod"},
+			wantYes:  true,
+		},
+		{
+			name:     "positive - short option at the end",
+			args:     []string{"ktx", "-", "-y"},
+			wantArgs: []string{"ktx", "-"},
+			wantYes:  true,
+		},
+		{
+			name:     "positive - options of the command behind --",
+			args:     []string{"ktx", "exec", "prod", "--", "echo", "-y", "--yes", "hi"},
+			wantArgs: []string{"ktx", "exec", "prod", "--", "echo", "-y", "--yes", "hi"},
+		},
+		{
+			name:     "positive - options of the command without --",
+			args:     []string{"ktx", "exec", "prod", "echo", "-y"},
+			wantArgs: []string{"ktx", "exec", "prod", "echo", "-y"},
+		},
+		{
+			name:     "positive - option before the command",
+			args:     []string{"ktx", "-y", "each", "env=prod", "--", "kubectl", "delete", "-y"},
+			wantArgs: []string{"ktx", "each", "env=prod", "--", "kubectl", "delete", "-y"},
+			wantYes:  true,
+		},
+		{
+			name:     "positive - option before the shell",
+			args:     []string{"ktx", "--yes", "shell", "prod"},
+			wantArgs: []string{"ktx", "shell", "prod"},
+			wantYes:  true,
+		},
+		{
+			name:     "positive - context named like a command",
+			args:     []string{"ktx", "-c", "exec", "--yes"},
+			wantArgs: []string{"ktx", "-c", "exec"},
+			wantYes:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotArgs, gotYes := withoutYes(tt.args)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+			assert.Equal(t, tt.wantYes, gotYes)
+		})
+	}
+}
+
+func Test_confirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:  "positive - name typed",
+			input: "prod\n",
+		},
+		{
+			name:  "positive - without newline",
+			input: " prod ",
+		},
+		{
+			name:    "negative - other name",
+			input:   "dev\n",
+			wantErr: k8sctx.ErrProtected,
+		},
+		{
+			name:    "negative - no input",
+			input:   "",
+			wantErr: k8sctx.ErrProtected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &strings.Builder{}
+			err := confirm("prod", strings.NewReader(tt.input), out)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Contains(t, out.String(), "'prod' is protected")
+		})
+	}
+}
+
+func Test_confirmProtected(t *testing.T) {
+	kc := &k8sctx.KubeConf{
+		Alias: "x",
+		Contexts: []*k8sctx.ContextEntry{
+			{Name: "a", Alias: "prod", Metadata: map[string]any{"protected": "true"}},
+			{Name: "b", Alias: "dev"},
+		},
+	}
+	// a pipe as stdin for a non-interactive caller
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+		assumeYes = false
+	})
+
+	assert.ErrorIs(t, confirmProtected(kc, "a"), k8sctx.ErrProtected)
+	assert.NoError(t, confirmProtected(kc, "b"))
+	assumeYes = true
+	assert.NoError(t, confirmProtected(kc, "a"))
+}
+
+func Test_confirmAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "positive - number typed", input: "2\n"},
+		{name: "negative - other number", input: "1\n", wantErr: k8sctx.ErrProtected},
+		{name: "negative - name typed", input: "prod\n", wantErr: k8sctx.ErrProtected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &strings.Builder{}
+			err := confirmAll([]string{"prod", "live"}, strings.NewReader(tt.input), out)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Contains(t, out.String(), "'prod', 'live' are protected")
+		})
+	}
+}
+
+func Test_childCommands_protected(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses true as command")
+	}
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("SHELL", "true")
+	t.Cleanup(func() { os.RemoveAll("testdata/isolated") })
+	orig, err := os.ReadFile("testdata/contexts_t.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.WriteFile("testdata/contexts_t.yaml", orig, 0600) })
+	contexts := "- kubeconfig: testdata/kube.config\n  name: aws:dev:accountId:eu-central-1:cluster1\n" +
+		"- kubeconfig: testdata/kube.config\n  name: aws:prod:accountId:us-east-1:cluster1\n  protected: true\n"
+	if err := os.WriteFile("testdata/contexts_t.yaml", []byte(contexts), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a pipe as stdin for a non-interactive caller
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+		assumeYes = false
+	})
+
+	prod := "aws:prod:accountId:us-east-1:cluster1"
+	commands := map[string][]string{
+		"shell": {"ktx", "shell", prod},
+		"exec":  {"ktx", "exec", prod, "--", "true"},
+		"each":  {"ktx", "each", "t", "--", "true"},
+	}
+	for name, args := range commands {
+		t.Run(name, func(t *testing.T) {
+			_, err := runWith(args)
+			assert.ErrorIs(t, err, k8sctx.ErrProtected)
+
+			banner := captureStderr(t, func() {
+				_, err = runWith(append([]string{"ktx", "-y"}, args[1:]...))
+			})
+			assert.NoError(t, err)
+			assert.Contains(t, banner, "PROTECTED CONTEXT: "+prod)
+			assert.NotContains(t, banner, "PROTECTED CONTEXT: aws:dev")
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterbueschel/k8sctx"
+)
+
+const (
+	// shellPIDEnv is set by the ktx shell function to the process id of the
+	// shell. It lets ktx remove the files of a session after its shell exited.
+	shellPIDEnv = "KTX_SHELL_PID"
+	// sessionMaxAge is the time after which the files of a session are
+	// removed, if the process id of its shell is unknown.
+	sessionMaxAge = 7 * 24 * time.Hour
+)
+
+var errNoSession = errors.New("no active session found in this shell")
+
+// activeSession returns the session of the current shell or nil. A session
+// is active if the KTX_SESSION variable is set and its kube config is the
+// first one in the KUBECONFIG variable.
+func activeSession(c *k8sctx.Config) *k8sctx.Session {
+	id := os.Getenv(k8sctx.SessionEnv)
+	if id == "" {
+		return nil
+	}
+	s := c.Session(id)
+	paths := filepath.SplitList(os.Getenv("KUBECONFIG"))
+	if len(paths) == 0 || paths[0] != s.KubeConfig {
+		return nil
+	}
+	return s
+}
+
+// switcherFor returns the active session of the shell together with its
+// state. Without a session, the global config and state are returned.
+func switcherFor(c *k8sctx.Config) (k8sctx.Switcher, *k8sctx.State) {
+	if s := activeSession(c); s != nil {
+		return s, s.State
+	}
+	return c, c.State
+}
+
+// kubeConfigsWithout returns the kube config files of the KUBECONFIG variable
+// without the session kube configs. If the variable is not set, the kube
+// configs of the config.jsonnet are returned.
+func kubeConfigsWithout(c *k8sctx.Config) []string {
+	paths := []string{}
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if p != "" && !c.IsSessionFile(p) {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) > 0 {
+		return paths
+	}
+	for _, k := range c.KubeConfs {
+		paths = append(paths, k.Path)
+	}
+	return paths
+}
+
+// shellQuote quotes the value for POSIX shells.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// useSession switches the context only for the current shell. Like a direct
+// switch, it takes a context with a namespace (ctx/ns) or a selector and asks
+// before a protected context is used. It returns the shell code, which
+// activates the session. The code is evaluated by the ktx shell function
+// (see "ktx init") or manually like:
+//
+//	eval "$(ktx -s prod)"
+func useSession(context string) (string, error) {
+	c, err := loadConfigs()
+	if err != nil {
+		return "", err
+	}
+	kcnf, ctx, namespace, err := lookupOrSelect(c, context)
+	if err != nil {
+		return "", err
+	}
+	if err := confirmProtected(kcnf, ctx.Name); err != nil {
+		return "", err
+	}
+	id := os.Getenv(k8sctx.SessionEnv)
+	if id == "" {
+		if err := c.PruneSessions(sessionMaxAge); err != nil {
+			fmt.Fprintln(os.Stderr, warningMessageStyle("warning: failed to remove stale sessions: "+err.Error()))
+		}
+		if id, err = k8sctx.NewSessionID(); err != nil {
+			return "", fmt.Errorf("failed to create session: %w", err)
+		}
+	}
+	s := c.Session(id)
+	s.PID = shellPID()
+	if err := s.SwitchToNamespace(kcnf, ctx.Name, namespace); err != nil {
+		return "", err
+	}
+	printBanner(kcnf, ctx.Name)
+	paths := append([]string{s.KubeConfig}, kubeConfigsWithout(c)...)
+	return shellCode(
+		envVar{name: k8sctx.SessionEnv, value: s.ID},
+		envVar{name: "KUBECONFIG", value: strings.Join(paths, string(os.PathListSeparator))},
+	), nil
+}
+
+// shellPID returns the process id of the shell, which the ktx shell function
+// passes in KTX_SHELL_PID, or 0.
+func shellPID() int {
+	pid, err := strconv.Atoi(os.Getenv(shellPIDEnv))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// endSession removes the session files of the current shell and returns the
+// shell code to restore the KUBECONFIG variable.
+func endSession() (string, error) {
+	c, err := loadConfigs()
+	if err != nil {
+		return "", err
+	}
+	s := activeSession(c)
+	if s == nil {
+		return "", errNoSession
+	}
+	if err := s.Remove(); err != nil {
+		return "", fmt.Errorf("failed to remove session files: %w", err)
+	}
+	return shellCode(
+		envVar{name: k8sctx.SessionEnv, unset: true},
+		envVar{name: "KUBECONFIG", value: strings.Join(kubeConfigsWithout(c), string(os.PathListSeparator))},
+	), nil
+}
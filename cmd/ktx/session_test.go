@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_session(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv(k8sctx.SessionEnv, "test")
+	t.Setenv("KUBECONFIG", "")
+	t.Cleanup(func() { os.RemoveAll("testdata/sessions") })
+	sessionConfig := filepath.Join("testdata", "sessions", "test.kubeconfig")
+	state, err := os.ReadFile("testdata/.state")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := useSession("aws:dev:accountId:eu-central-1:cluster1")
+	if err != nil {
+		t.Fatalf("useSession() error = %v", err)
+	}
+	assert.Equal(t, evalMarker+"\nexport KTX_SESSION='test'\nexport KUBECONFIG='"+
+		sessionConfig+string(os.PathListSeparator)+"testdata/kube.config'", got)
+
+	_, err = endSession()
+	assert.ErrorIs(t, err, errNoSession, "session is not active without KUBECONFIG")
+
+	t.Setenv("KUBECONFIG", sessionConfig+string(os.PathListSeparator)+"testdata/kube.config")
+	if _, err := directlyUse("aws:prod:accountId:us-east-1:cluster1/web"); err != nil {
+		t.Fatalf("directlyUse() error = %v", err)
+	}
+	current, err := getCurrentContext()
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:prod:accountId:us-east-1:cluster1", current)
+	assert.Equal(t, "web", sessionNamespace(t, sessionConfig))
+
+	back, err := switchBack()
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:dev:accountId:eu-central-1:cluster1", back)
+
+	// "ktx -" brings back the namespace
+	back, err = switchBack()
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:prod:accountId:us-east-1:cluster1", back)
+	assert.Equal(t, "web", sessionNamespace(t, sessionConfig))
+
+	unchanged, err := os.ReadFile("testdata/.state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(state), string(unchanged), "global state was changed")
+
+	got, err = endSession()
+	if err != nil {
+		t.Fatalf("endSession() error = %v", err)
+	}
+	assert.Equal(t, evalMarker+"\nunset KTX_SESSION\nexport KUBECONFIG='testdata/kube.config'", got)
+	assert.NoFileExists(t, sessionConfig)
+	assert.False(t, strings.Contains(got, "sessions"))
+}
+
+func Test_useSession(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv(k8sctx.SessionEnv, "")
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv(shellPIDEnv, strconv.Itoa(os.Getpid()))
+	t.Cleanup(func() { os.RemoveAll("testdata/sessions") })
+	contexts, err := os.ReadFile("testdata/contexts_t.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.WriteFile("testdata/contexts_t.yaml", contexts, 0600) })
+	protected := "- name: aws:dev:accountId:eu-central-1:cluster1\n" +
+		"- name: aws:prod:accountId:us-east-1:cluster1\n  protected: true\n"
+	if err := os.WriteFile("testdata/contexts_t.yaml", []byte(protected), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a pipe as stdin for a non-interactive caller
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+
+	_, err = useSession("aws:prod:accountId:us-east-1:cluster1")
+	assert.ErrorIs(t, err, k8sctx.ErrProtected)
+	assert.NoDirExists(t, "testdata/sessions", "session of a refused switch")
+
+	got, err := useSession("aws:dev:accountId:eu-central-1:cluster1/web")
+	if err != nil {
+		t.Fatalf("useSession() error = %v", err)
+	}
+	id := regexp.MustCompile(`KTX_SESSION='(\w+)'`).FindStringSubmatch(got)
+	if !assert.Len(t, id, 2) {
+		return
+	}
+	assert.Equal(t, "web", sessionNamespace(t, filepath.Join("testdata", "sessions", id[1]+".kubeconfig")))
+	s := &k8sctx.Session{State: &k8sctx.State{Filename: filepath.Join("testdata", "sessions", id[1]+".state")}}
+	assert.NoError(t, s.GetState())
+	assert.Equal(t, os.Getpid(), s.ShellPID)
+}
+
+// sessionNamespace returns the namespace of the current context of the
+// session kube config.
+func sessionNamespace(t *testing.T, path string) string {
+	t.Helper()
+	k, err := k8sctx.GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _, err := k.GetContextBy(k.CurrentContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx.Namespace
+}
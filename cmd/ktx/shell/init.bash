@@ -0,0 +1,59 @@
+# ktx shell integration for bash. Add the following line to your ~/.bashrc:
+#
+#   eval "$(ktx init bash)"
+
+# ktx wraps the binary and evaluates its output, if it contains shell code
+# (for example from "ktx -s <context>"). Commands, which start other
+# programs, are called directly.
+ktx() {
+  local out rc
+  case "$1" in
+    shell | exec | each) command ktx "$@"; return ;;
+  esac
+  out="$(KTX_SHELL=bash KTX_SHELL_PID=$$ command ktx "$@")"
+  rc=$?
+  case "$out" in
+    "# ktx:eval"*) eval "$out" ;;
+    ?*) printf '%s\n' "$out" ;;
+  esac
+  return $rc
+}
+
+# ktx_prompt prints the current context for the prompt.
+ktx_prompt() {
+  local ctx
+  ctx="$(command ktx -c 2>/dev/null)" || return 0
+  case "$ctx" in
+    "" | *" "*) ;;
+    *) printf '(%s) ' "$ctx" ;;
+  esac
+}
+
+_ktx_complete() {
+  local cur prev words
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  prev="${COMP_WORDS[COMP_CWORD-1]}"
+  if declare -F _get_comp_words_by_ref >/dev/null; then
+    _get_comp_words_by_ref -n : cur prev
+  fi
+  case "$COMP_CWORD" in
+    1) words="- -c -s -h -v init ns history list shell exec each $(command ktx -complete 2>/dev/null)" ;;
+    2)
+      case "$prev" in
+        -c | -is | -current | -s | -session | shell | exec | each) words="$(command ktx -complete -contexts 2>/dev/null)" ;;
+        init) words="bash zsh fish" ;;
+        -*) words="" ;;
+        *) words="$(command ktx -complete "$prev" 2>/dev/null)" ;;
+      esac
+      ;;
+  esac
+  COMPREPLY=($(compgen -W "$words" -- "$cur"))
+  if declare -F __ltrim_colon_completions >/dev/null; then
+    __ltrim_colon_completions "$cur"
+  fi
+}
+complete -F _ktx_complete ktx
+{{- if .Prompt }}
+
+PS1='$(ktx_prompt)'"$PS1"
+{{- end }}
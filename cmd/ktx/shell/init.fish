@@ -0,0 +1,51 @@
+# ktx shell integration for fish. Add the following line to your
+# ~/.config/fish/config.fish:
+#
+#   ktx init fish | source
+
+# ktx wraps the binary and evaluates its output, if it contains shell code
+# (for example from "ktx -s <context>"). Commands, which start other
+# programs, are called directly.
+function ktx --description 'kubernetes context switcher'
+    if contains -- "$argv[1]" shell exec each
+        command ktx $argv
+        return
+    end
+    set -l out (KTX_SHELL=fish KTX_SHELL_PID=$fish_pid command ktx $argv)
+    set -l rc $status
+    if test "$out[1]" = '# ktx:eval'
+        printf '%s\n' $out | source
+    else if set -q out[1]
+        printf '%s\n' $out
+    end
+    return $rc
+end
+
+# ktx_prompt prints the current context for the prompt.
+function ktx_prompt --description 'print the current kubernetes context'
+    set -l ctx (command ktx -c 2>/dev/null)
+    or return 0
+    if test -n "$ctx"; and not string match -q -- '* *' "$ctx"
+        printf '(%s) ' $ctx
+    end
+end
+
+function __ktx_arg --description 'print the n-th argument of the ktx command line'
+    set -l args (commandline -opc)
+    test (count $args) -eq $argv[1]; or return 1
+    test $argv[1] -lt 2; or printf '%s' $args[2]
+end
+
+complete -c ktx -f
+complete -c ktx -n '__ktx_arg 1' -a '- -c -s -h -v init ns history list shell exec each (command ktx -complete 2>/dev/null)'
+complete -c ktx -n 'contains -- (__ktx_arg 2) -c -is -current -s -session shell exec each' -a '(command ktx -complete -contexts 2>/dev/null)'
+complete -c ktx -n 'test (__ktx_arg 2) = init' -a 'bash zsh fish'
+complete -c ktx -n 'set -l arg (__ktx_arg 2); and not string match -q -- "-*" $arg; and not contains -- $arg init ns history list shell exec each' -a '(command ktx -complete (__ktx_arg 2) 2>/dev/null)'
+{{- if .Prompt }}
+
+functions -c fish_prompt __ktx_fish_prompt
+function fish_prompt
+    ktx_prompt
+    __ktx_fish_prompt
+end
+{{- end }}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const (
+	// shellEnv is set by the ktx shell function to the name of the shell. It
+	// tells the binary, that its output is evaluated by the shell function.
+	shellEnv = "KTX_SHELL"
+	// evalMarker is the first line of an output, which must be evaluated by
+	// the ktx shell function.
+	evalMarker = "# ktx:eval"
+)
+
+var (
+	//go:embed shell/init.*
+	shellScripts embed.FS
+
+	shells = []string{"bash", "zsh", "fish"}
+)
+
+// envVar is an environment variable, which is set by the shell function. An
+// unset variable is removed from the environment of the shell.
+type envVar struct {
+	name  string
+	value string
+	unset bool
+}
+
+// initShell returns the shell integration for the given shell, like:
+//
+//	eval "$(ktx init bash)"
+//
+// With the option "-prompt" the current context is added to the prompt.
+func initShell(args []string) (string, error) {
+	shell, prompt := "", false
+	for _, arg := range args {
+		switch arg {
+		case "-p", "-prompt":
+			prompt = true
+		default:
+			shell = arg
+		}
+	}
+	script, err := shellScripts.ReadFile("shell/init." + shell)
+	if err != nil {
+		return "", fmt.Errorf("unsupported shell '%s', use one of: %s", shell, strings.Join(shells, ", "))
+	}
+	tmpl, err := template.New(shell).Parse(string(script))
+	if err != nil {
+		return "", fmt.Errorf("parse %s template %w", shell, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, struct{ Prompt bool }{prompt}); err != nil {
+		return "", fmt.Errorf("execute %s template %w", shell, err)
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// wrapped returns true if ktx was called by the shell function.
+func wrapped() bool {
+	return os.Getenv(shellEnv) != ""
+}
+
+// shellCode returns the code, which sets the environment variables in the
+// shell given by KTX_SHELL. POSIX code is returned for bash, zsh or if ktx
+// was not called by the shell function.
+func shellCode(vars ...envVar) string {
+	fish := os.Getenv(shellEnv) == "fish"
+	lines := []string{evalMarker}
+	for _, v := range vars {
+		switch {
+		case fish && v.unset:
+			lines = append(lines, "set -e "+v.name)
+		case fish:
+			lines = append(lines, fmt.Sprintf("set -gx %s %s", v.name, fishQuote(v.value)))
+		case v.unset:
+			lines = append(lines, "unset "+v.name)
+		default:
+			lines = append(lines, fmt.Sprintf("export %s=%s", v.name, shellQuote(v.value)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fishQuote quotes the value for the fish shell.
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// complete prints the candidates for the shell completion, one per line.
+// Without an argument the config aliases are returned, with "-contexts" the
+// aliases of all contexts and with a config alias only its context aliases.
+func complete(args []string) (string, error) {
+	// no writes and no warnings while the user presses tab
+	c, err := readConfigs()
+	if err != nil {
+		return "", err
+	}
+	if !c.Synced() {
+		if err := c.SyncInMemory(); err != nil {
+			return "", err
+		}
+	}
+	candidates := []string{}
+	if len(args) == 0 {
+		for _, k := range c.KubeConfs {
+			candidates = append(candidates, k.Alias)
+		}
+		return strings.Join(candidates, "\n"), nil
+	}
+	filter := args[0]
+	if filter == "-contexts" {
+		filter = ""
+	}
+	for _, item := range c.CreateListItems(filter, "") {
+		candidates = append(candidates, item.Name)
+	}
+	return strings.Join(candidates, "\n"), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func Test_initShell(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		golden  string
+		wantErr bool
+	}{
+		{name: "bash", args: []string{"bash"}, golden: "init.bash.golden"},
+		{name: "bash with prompt", args: []string{"bash", "-prompt"}, golden: "init_prompt.bash.golden"},
+		{name: "zsh", args: []string{"zsh"}, golden: "init.zsh.golden"},
+		{name: "zsh with prompt", args: []string{"-p", "zsh"}, golden: "init_prompt.zsh.golden"},
+		{name: "fish", args: []string{"fish"}, golden: "init.fish.golden"},
+		{name: "fish with prompt", args: []string{"fish", "-prompt"}, golden: "init_prompt.fish.golden"},
+		{name: "negative - unknown shell", args: []string{"tcsh"}, wantErr: true},
+		{name: "negative - no shell", args: []string{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initShell(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("initShell() error = %v", err)
+			}
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(want), got)
+		})
+	}
+}
+
+func Test_shellCode(t *testing.T) {
+	vars := []envVar{
+		{name: "KTX_SESSION", unset: true},
+		{name: "KUBECONFIG", value: `/it's/a\path`},
+	}
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{shell: "", want: evalMarker + "\nunset KTX_SESSION\nexport KUBECONFIG='/it'\\''s/a\\path'"},
+		{shell: "zsh", want: evalMarker + "\nunset KTX_SESSION\nexport KUBECONFIG='/it'\\''s/a\\path'"},
+		{shell: "fish", want: evalMarker + "\nset -e KTX_SESSION\nset -gx KUBECONFIG '/it\\'s/a\\\\path'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			t.Setenv(shellEnv, tt.shell)
+			assert.Equal(t, tt.want, shellCode(vars...))
+		})
+	}
+}
+
+func Test_complete(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "config aliases", args: []string{}, want: "t"},
+		{
+			name: "all contexts",
+			args: []string{"-contexts"},
+			want: "aws:dev:accountId:eu-central-1:cluster1\naws:prod:accountId:us-east-1:cluster1",
+		},
+		{
+			name: "contexts of config",
+			args: []string{"t"},
+			want: "aws:dev:accountId:eu-central-1:cluster1\naws:prod:accountId:us-east-1:cluster1",
+		},
+		{name: "unknown config", args: []string{"x"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := complete(tt.args)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/peterbueschel/k8sctx"
+)
+
+const (
+	// contextEnv holds the context of a shell started by "ktx shell".
+	contextEnv = "KTX_CONTEXT"
+	// levelEnv holds the nesting level of shells started by "ktx shell".
+	levelEnv = "KTX_SHELL_LEVEL"
+)
+
+var errMissingContext = errors.New("missing context alias")
+
+// userShell returns the login shell of the user.
+func userShell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	if runtime.GOOS == "windows" {
+		if sh := os.Getenv("COMSPEC"); sh != "" {
+			return sh
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// childEnv returns the environment of ktx for a child process with the given
+// variables set. The variables of the ktx shell function and of a session
+// are removed, because they don't apply to the child.
+func childEnv(vars map[string]string) []string {
+	env := []string{}
+	for _, e := range os.Environ() {
+		name, _, _ := strings.Cut(e, "=")
+		if _, exists := vars[name]; exists || name == shellEnv || name == k8sctx.SessionEnv {
+			continue
+		}
+		env = append(env, e)
+	}
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// runChild runs the command in the foreground. Interrupts and hangups are
+// left to the child, so that ktx can clean up after the child has finished.
+func runChild(cmd *exec.Cmd) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sig)
+	return cmd.Run()
+}
+
+// isolatedContext returns the context of the shell, if it was started by
+// "ktx shell".
+func isolatedContext(c *k8sctx.Config) string {
+	paths := filepath.SplitList(os.Getenv("KUBECONFIG"))
+	if len(paths) != 1 || !c.IsIsolatedFile(paths[0]) {
+		return ""
+	}
+	return os.Getenv(contextEnv)
+}
+
+// startShell starts the shell of the user with a temporary kube config, which
+// holds only the given context. The kube config files and the .state file are
+// not changed and the temporary kube config is removed, when the shell exits.
+func startShell(context string) (string, error) {
+	c, err := peekConfigs()
+	if err != nil {
+		return "", err
+	}
+	kcnf, ctx, err := c.LookupContext(context)
+	if err != nil {
+		return "", err
+	}
+	if err := confirmProtected(kcnf, ctx.Name); err != nil {
+		return "", err
+	}
+	printBanner(kcnf, ctx.Name)
+	path, remove, err := c.Isolate(kcnf, ctx.Name)
+	if err != nil {
+		return "", err
+	}
+
+	level := 1
+	if l, err := strconv.Atoi(os.Getenv(levelEnv)); err == nil {
+		level = l + 1
+	}
+	if outer := isolatedContext(c); outer != "" {
+		fmt.Fprintln(os.Stderr, warningMessageStyle(
+			fmt.Sprintf("nested shell: '%s' inside the shell of '%s' (level %d)", context, outer, level)))
+	}
+	cmd := exec.Command(userShell())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = childEnv(map[string]string{
+		"KUBECONFIG": path,
+		contextEnv:   context,
+		levelEnv:     strconv.Itoa(level),
+	})
+	err = runChild(cmd)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// the exit code of a shell is the one of its last command
+		err = nil
+	}
+	if err := errors.Join(err, remove()); err != nil {
+		return "", fmt.Errorf("shell for context '%s' failed: %w", context, err)
+	}
+	return fmt.Sprintf("left the shell of context '%s'", context), nil
+}
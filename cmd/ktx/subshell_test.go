@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_startShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as $SHELL")
+	}
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv(levelEnv, "")
+	t.Setenv(shellEnv, "bash")
+	t.Cleanup(func() { os.RemoveAll("testdata/isolated") })
+	kubeConfig, err := os.ReadFile("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the "shell" stores its environment and the content of its kube config
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	shell := filepath.Join(dir, "shell")
+	script := "#!/bin/sh\n" +
+		`echo "$KTX_CONTEXT $KTX_SHELL_LEVEL ${KTX_SHELL:-unset} $KUBECONFIG" > ` + out + "\n" +
+		`cat "$KUBECONFIG" >> ` + out + "\n" +
+		"exit 3\n"
+	if err := os.WriteFile(shell, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHELL", shell)
+
+	got, err := startShell("aws:dev:accountId:eu-central-1:cluster1")
+	if err != nil {
+		t.Fatalf("startShell() error = %v", err)
+	}
+	assert.Equal(t, "left the shell of context 'aws:dev:accountId:eu-central-1:cluster1'", got)
+
+	env, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, content, _ := strings.Cut(string(env), "\n")
+	fields := strings.Fields(first)
+	if assert.Len(t, fields, 4) {
+		assert.Equal(t, []string{"aws:dev:accountId:eu-central-1:cluster1", "1", "unset"}, fields[:3])
+		assert.Equal(t, filepath.Join("testdata", "isolated"), filepath.Dir(fields[3]))
+		assert.NoFileExists(t, fields[3], "temporary kube config was not removed")
+	}
+	assert.Contains(t, content, "current-context: aws:dev:accountId:eu-central-1:cluster1")
+	assert.NotContains(t, content, "aws:prod:accountId:us-east-1:cluster1")
+
+	unchanged, err := os.ReadFile("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(kubeConfig), string(unchanged), "kube config was changed")
+
+	t.Setenv(levelEnv, "1")
+	if _, err := startShell("aws:prod:accountId:us-east-1:cluster1"); err != nil {
+		t.Fatalf("startShell() error = %v", err)
+	}
+	env, err = os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(string(env), "aws:prod:accountId:us-east-1:cluster1 2 "), "nested level")
+
+	_, err = startShell("unknown")
+	assert.Error(t, err)
+}
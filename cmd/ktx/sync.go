@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peterbueschel/k8sctx"
+)
+
+const inSync = "All kube configs and contexts files are in sync."
+
+// syncConfigs syncs the kube configs and contexts files and returns a report
+// of the changes. Unresolved namespace drifts are prompted for, if stdin is a
+// terminal. Options:
+//
+//	-n|-dry-run  only report the changes
+//	-j|-json     report the changes as JSON
+func syncConfigs(args []string) (string, error) {
+	dryRun, asJSON := false, false
+	for _, arg := range args {
+		switch strings.TrimPrefix(arg, "-") {
+		case "-dry-run", "dry-run", "n":
+			dryRun = true
+		case "-json", "json", "j":
+			asJSON = true
+		default:
+			return "", fmt.Errorf("unknown option '%s' for 'ktx sync'", arg)
+		}
+	}
+	c, err := readConfigs()
+	if err != nil {
+		return "", err
+	}
+	var reports []k8sctx.SyncReport
+	if dryRun {
+		reports, err = c.Sync(true)
+	} else {
+		reports, err = syncLocked(c, false)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !dryRun {
+		if !asJSON && isTerminal(os.Stdin) {
+			if err := resolveDrifts(c, reports, os.Stdin, os.Stderr); err != nil {
+				return "", err
+			}
+		}
+		if err := reloadAfter(c, reports); err != nil {
+			return "", err
+		}
+	}
+	printWarnings(c)
+
+	if asJSON {
+		out, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	return syncReport(reports), nil
+}
+
+// syncReport returns the changed reports in the diff like format.
+func syncReport(reports []k8sctx.SyncReport) string {
+	lines := []string{}
+	for _, r := range reports {
+		if out := r.String(); out != "" {
+			lines = append(lines, out)
+		}
+	}
+	if len(lines) == 0 {
+		return inSync
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolveDrifts asks for every unresolved namespace drift, which side should
+// win. Resolved drifts are moved into the namespace changes of the report.
+func resolveDrifts(c *k8sctx.Config, reports []k8sctx.SyncReport, in io.Reader, out io.Writer) error {
+	answers := bufio.NewScanner(in)
+	for idx := range reports {
+		r := &reports[idx]
+		kcnf := c.GetKubeConfigByAlias(r.Alias)
+		unresolved := []k8sctx.NamespaceDrift{}
+		for _, d := range r.Drifts {
+			options := "[k]ubeconfig, [c]onfig or [s]kip"
+			if d.Forced() {
+				options = "[c]onfig or [s]kip"
+			}
+			fmt.Fprintf(out, "%s\nkeep the namespace of %s? ", d, options)
+			if !answers.Scan() {
+				return answers.Err()
+			}
+			switch strings.ToLower(strings.TrimSpace(answers.Text())) {
+			case "k", "kubeconfig":
+				if d.Forced() {
+					unresolved = append(unresolved, d)
+					continue
+				}
+				if err := kcnf.ResolveDrift(d, k8sctx.DriftKubeConfigWins); err != nil {
+					return err
+				}
+				r.FileNamespaces = append(r.FileNamespaces,
+					k8sctx.NamespaceChange{Context: d.Context, From: d.ContextFile, To: d.KubeConfig})
+			case "c", "config":
+				if err := kcnf.ResolveDrift(d, k8sctx.DriftConfigWins); err != nil {
+					return err
+				}
+				r.Namespaces = append(r.Namespaces,
+					k8sctx.NamespaceChange{Context: d.Context, From: d.KubeConfig, To: d.Config})
+			default:
+				unresolved = append(unresolved, d)
+			}
+		}
+		r.Drifts = unresolved
+	}
+	return nil
+}
+
+// isTerminal returns true if the file is a character device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
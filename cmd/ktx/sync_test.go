@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peterbueschel/k8sctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_syncConfigs(t *testing.T) {
+	t.Setenv("KTX_CONFIG_DIR", "testdata")
+	t.Setenv("KUBECONFIG", "")
+	got, err := syncConfigs(nil)
+	if err != nil {
+		t.Fatalf("syncConfigs() error = %v", err)
+	}
+	before, err := os.Stat("testdata/contexts_t.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, got)
+
+	got, err = syncConfigs([]string{"--dry-run"})
+	assert.NoError(t, err)
+	assert.Equal(t, inSync, got)
+
+	got, err = syncConfigs([]string{"-n", "-json"})
+	assert.NoError(t, err)
+	reports := []k8sctx.SyncReport{}
+	if err := json.Unmarshal([]byte(got), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "t", reports[0].Alias)
+		assert.False(t, reports[0].Changed())
+	}
+
+	// synced files are not written again
+	if _, err := loadConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat("testdata/contexts_t.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, os.SameFile(before, after), "contexts file was written")
+
+	_, err = syncConfigs([]string{"-force"})
+	assert.Error(t, err)
+}
+
+func Test_resolveDrifts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	kube := "contexts:\n- name: a\n  context: {cluster: a, user: a, namespace: dev}\n" +
+		"- name: b\n  context: {cluster: a, user: a}\ncurrent-context: a\n"
+	if err := os.WriteFile(path, []byte(kube), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := k8sctx.GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc := &k8sctx.KubeConf{
+		Path: path, Alias: "x", KubeConfig: k,
+		ContextFile: filepath.Join(dir, "contexts_x.yaml"),
+		Contexts: []*k8sctx.ContextEntry{
+			{Name: "a", Namespace: "web"},
+			{Name: "b", Namespace: "web"},
+		},
+	}
+	c := &k8sctx.Config{Dir: dir, KubeConfs: []*k8sctx.KubeConf{kc}}
+	drifts, err := kc.NamespaceDrifts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := []k8sctx.SyncReport{{Alias: "x", Drifts: drifts}}
+
+	out := &strings.Builder{}
+	err = resolveDrifts(c, reports, strings.NewReader("c\ns\n"), out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "namespace drift of 'a'")
+	assert.Equal(t, []k8sctx.NamespaceChange{{Context: "a", From: "dev", To: "web"}}, reports[0].Namespaces)
+	if assert.Len(t, reports[0].Drifts, 1) {
+		assert.Equal(t, "b", reports[0].Drifts[0].Context)
+	}
+	k, err = k8sctx.GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _, _ := k.GetContextBy("a")
+	assert.Equal(t, "web", ctx.Namespace)
+}
+
+func Test_describe(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  k8sctx.ContextItem
+		want string
+	}{
+		{
+			name: "in sync",
+			ctx:  k8sctx.ContextItem{Description: "namespace: web"},
+			want: "namespace: web",
+		},
+		{
+			name: "drift",
+			ctx: k8sctx.ContextItem{
+				Description: "namespace: web",
+				Drift:       &k8sctx.NamespaceDrift{KubeConfig: "dev", Config: "web"},
+			},
+			want: "⚠ namespace drift (kubeconfig: dev, config: web), namespace: web",
+		},
+		{
+			name: "drift without description",
+			ctx:  k8sctx.ContextItem{Drift: &k8sctx.NamespaceDrift{Config: "web"}},
+			want: "⚠ namespace drift (kubeconfig: <none>, config: web)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, describe(tt.ctx))
+		})
+	}
+}
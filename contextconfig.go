@@ -19,11 +19,18 @@ var (
 	ErrParseConfig    = errors.New("failed to parse context config file")
 	ErrReadStateFile  = errors.New("failed to parse state file")
 	ErrParseStateFile = errors.New("failed to parse state file")
+	// ErrAmbiguousContext is returned if a context name or alias exists in
+	// more than one kube config.
+	ErrAmbiguousContext = errors.New("ambiguous context")
+	// ErrDuplAlias is added to the warnings, if two kube configs have the
+	// same alias.
+	ErrDuplAlias = errors.New("duplicated kube config alias")
 )
 
 type (
 	// Config is the main struct, which is generated from the config file and
-	// holds one or more kube configs.
+	// holds one or more kube configs. A Config is not safe for concurrent
+	// use: goroutines, which change the files, need a Config each (see Lock).
 	Config struct {
 		// Dir of the config files
 		Dir string
@@ -35,9 +42,40 @@ type (
 		// State contains the name of the state file
 		// LastConf, CurrentContext and LastContext
 		*State `json:"state"`
+		// StrictPermissions refuses to use kube configs with credentials,
+		// which are readable by the group or others. Otherwise only a warning
+		// is added to the Warnings.
+		StrictPermissions bool `json:"strict_permissions"`
+		// OrphanedContexts sets how SyncContexts handles contexts, which were
+		// removed from their kube config: OrphansMark (default) keeps them
+		// with "stale: true", OrphansDelete removes them.
+		OrphanedContexts string `json:"orphaned_contexts"`
+		// NamespaceDrift sets how Sync handles a namespace, which differs
+		// between the kube config and the config.jsonnet: DriftConfigWins,
+		// DriftKubeConfigWins or DriftAsk. By default the kube config wins.
+		NamespaceDrift string `json:"namespace_drift"`
+		// Description is a Go template for the description line of the
+		// contexts in the list, like "{{.environment}} · ns={{.namespace}}".
+		// A kube config can override it.
+		Description string `json:"description"`
+		// HideFields are left out of the description line, if no template is
+		// set.
+		HideFields []string `json:"hide_fields"`
+		// Terminal is the id of the terminal, whose previous context is
+		// tracked in the state (see TerminalID). It is empty by default.
+		Terminal string `json:"-"`
+		// Warnings collects problems found while reading the config, which
+		// don't prevent ktx from working.
+		Warnings []error `json:"-"`
+		// digest is the fingerprint of the input files (see Synced).
+		digest string
+		// lock of the config dir (see Lock).
+		lock *dirLock
 	}
 	// State is used to switch back to the previous contexts.
 	State struct {
+		// Version of the schema of the state file.
+		Version int `yaml:"version"`
 		// Filename of the state file
 		Filename string `yaml:"filename"`
 		// CurrentConf holds the name of the curently used kube config. This
@@ -51,6 +89,24 @@ type (
 		// LastContext together with LastConf will be used to switch between
 		// two contexts.
 		LastContext string `yaml:"lastContext"`
+		// CurrentNamespace and LastNamespace are the namespaces of the
+		// current and the last context.
+		CurrentNamespace string `yaml:"currentNamespace,omitempty"`
+		LastNamespace    string `yaml:"lastNamespace,omitempty"`
+		// Namespaces holds the last used namespace per kube config and
+		// context.
+		Namespaces map[string]map[string]string `yaml:"namespaces,omitempty"`
+		// History holds the last switches, the newest first. The first entry
+		// is the current context.
+		History []HistoryEntry `yaml:"history,omitempty"`
+		// Terminals holds the current and the previous context per terminal
+		// (see TerminalID).
+		Terminals map[string]Terminal `yaml:"terminals,omitempty"`
+		// ShellPID is the process id of the shell of a session.
+		ShellPID int `yaml:"shellPID,omitempty"`
+		// Origins holds the cluster and user per kube config and context,
+		// which are used to detect renamed contexts (see SyncContexts).
+		Origins map[string]map[string]Origin `yaml:"origins,omitempty"`
 	}
 	// KubeConf stores the content of a single kube config file.
 	KubeConf struct {
@@ -62,14 +118,40 @@ type (
 		ContextFile string `json:"context_file"`
 		// Contexts will be synced with the kube config file
 		// and extended by the GlobalConfig file
-		Contexts []map[string]string `json:"contexts"`
+		Contexts []*ContextEntry `json:"contexts"`
 		// KubeConfig holds the kube config file content
 		KubeConfig *KubeConfig `json:"-"`
+		// Description overrides the global description template for the
+		// contexts of this kube config.
+		Description string `json:"description"`
+		// HideFields are left out of the description line in addition to the
+		// global ones.
+		HideFields []string `json:"hide_fields"`
+		// origins of the contexts as stored in the .state file.
+		origins map[string]Origin
+		// lock of the config dir, which is shared with the Config.
+		lock *dirLock
 	}
 	// ContextItem is used in the "list" Model in the cmd/ktx.
 	ContextItem struct {
+		// Name is shown in the list. It is the alias or the name of the
+		// context, qualified by the kube config alias if it is not unique.
 		Name        string
 		Description string
+		// Config is the alias of the kube config and Context the name of
+		// the context inside the kube config. Together they identify the
+		// context.
+		Config  string
+		Context string
+		// Drift is set, if the namespace of the context differs between the
+		// kube config and the config.jsonnet.
+		Drift *NamespaceDrift
+		// Protected is true for a context, which needs a confirmation before
+		// it is used.
+		Protected bool
+		// Fields holds all fields of the context as strings, like
+		// "environment": "prod", for the search in the list.
+		Fields map[string]string
 	}
 )
 
@@ -86,14 +168,39 @@ func Get(config string) (*Config, error) {
 	}
 	parsedConfig.GlobalConfig = config
 	parsedConfig.setup()
+	if err := validOrphans(parsedConfig.OrphanedContexts); err != nil {
+		return nil, fmt.Errorf("%w: '%s', err: %w", ErrParseConfig, config, err)
+	}
+	if err := validDrift(parsedConfig.NamespaceDrift); err != nil {
+		return nil, fmt.Errorf("%w: '%s', err: %w", ErrParseConfig, config, err)
+	}
+
+	aliases := map[string]bool{}
+	for _, cnf := range parsedConfig.KubeConfs {
+		if aliases[cnf.Alias] {
+			parsedConfig.Warnings = append(parsedConfig.Warnings,
+				fmt.Errorf("%w '%s' in '%s'", ErrDuplAlias, cnf.Alias, config))
+		}
+		aliases[cnf.Alias] = true
+		if _, err := parsedConfig.descriptionTemplate(cnf); err != nil {
+			return nil, fmt.Errorf("%w: '%s', err: %w", ErrParseConfig, config, err)
+		}
+	}
 
 	for _, cnf := range parsedConfig.KubeConfs {
 		k, err := GetKubeConfig(cnf.Path)
 		if err != nil {
 			return nil, err
 		}
+		if err := k.CheckPermissions(); err != nil {
+			if parsedConfig.StrictPermissions {
+				return nil, err
+			}
+			parsedConfig.Warnings = append(parsedConfig.Warnings, err)
+		}
 		cnf.KubeConfig = k
 	}
+	parsedConfig.digest = parsedConfig.fingerprint(cnf)
 	return parsedConfig, nil
 }
 
@@ -113,6 +220,7 @@ func (c *Config) setup() {
 			)
 		}
 	}
+	c.setLock(newDirLock(c.Dir))
 }
 
 // read evaluates the jsonnet config file with the help of some custom importers.
@@ -142,34 +250,126 @@ func (c *Config) SyncNamespaces() error {
 }
 
 // SyncNamespaces loops over the contexts and updates the namespace in the
-// underlying kube config file.
+// underlying kube config file. Stale contexts are skipped.
 func (k *KubeConf) SyncNamespaces() error {
-	for _, ctx := range k.Contexts {
-		if err := k.KubeConfig.AddNamespaceTo(ctx["name"], ctx["namespace"]); err != nil {
+	unlock, err := k.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	changes, err := k.namespaceChanges()
+	if err != nil {
+		return err
+	}
+	for _, ch := range changes {
+		if err := k.KubeConfig.AddNamespaceTo(ch.Context, ch.To); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// namespaceChanges returns the namespaces of the contexts, which differ from
+// the ones in the kube config. Stale contexts are skipped.
+func (k *KubeConf) namespaceChanges() ([]NamespaceChange, error) {
+	changes := []NamespaceChange{}
+	for _, ctx := range k.Contexts {
+		if ctx.Namespace == "" || ctx.Bool(staleKey) {
+			continue
+		}
+		kctx, _, err := k.KubeConfig.GetContextBy(ctx.Name)
+		if err != nil {
+			return nil, err
+		}
+		if kctx.Namespace != ctx.Namespace {
+			changes = append(changes, NamespaceChange{Context: ctx.Name, From: kctx.Namespace, To: ctx.Namespace})
+		}
+	}
+	return changes, nil
+}
+
 // GetContextBy takes a name or alias of the desired context as argument and
-// returns the KubeConf, the context and its index.
-func (c *Config) GetContextBy(name string) (*KubeConf, map[string]string, int) {
+// returns the KubeConf, the context and its index. If the name exists in
+// multiple kube configs, the first one is returned; use LookupContext to
+// detect ambiguous names.
+func (c *Config) GetContextBy(name string) (*KubeConf, *ContextEntry, int) {
 	for _, cnf := range c.KubeConfs {
 		for idx, ctx := range cnf.Contexts {
-			if ctx["name"] == name {
+			if ctx.Name == name {
 				return cnf, ctx, idx
 			}
-			if alias, exists := ctx["alias"]; exists {
-				if alias == name {
-					return cnf, ctx, idx
-				}
+			if ctx.Alias != "" && ctx.Alias == name {
+				return cnf, ctx, idx
 			}
 		}
 	}
 	return nil, nil, -1
 }
 
+// LookupContext returns the KubeConf and the context for the given name or
+// alias. A name, which exists in more than one kube config, must be qualified
+// by the alias of the kube config, like "dev/minikube". Otherwise
+// ErrAmbiguousContext is returned, which lists the candidates. ErrNoContext
+// is returned if no context matches.
+func (c *Config) LookupContext(name string) (*KubeConf, *ContextEntry, error) {
+	matches := []*KubeConf{}
+	for _, cnf := range c.KubeConfs {
+		if cnf.Exists(name) {
+			matches = append(matches, cnf)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		ctx, _ := matches[0].GetContextBy(name)
+		return matches[0], ctx, nil
+	case 0:
+		if alias, ctxName, ok := strings.Cut(name, "/"); ok {
+			if cnf := c.GetKubeConfigByAlias(alias); cnf != nil {
+				if ctx, idx := cnf.GetContextBy(ctxName); idx != -1 {
+					return cnf, ctx, nil
+				}
+			}
+		}
+		return nil, nil, fmt.Errorf("%w with name '%s'", ErrNoContext, name)
+	}
+	candidates := []string{}
+	for _, cnf := range matches {
+		candidates = append(candidates, cnf.QualifiedName(name))
+	}
+	return nil, nil, fmt.Errorf("%w '%s', use one of: %s",
+		ErrAmbiguousContext, name, strings.Join(candidates, ", "))
+}
+
+// LookupContextNamespace works like LookupContext, but accepts in addition a
+// namespace behind the context, like "lab/monitoring" or
+// "dev/lab/monitoring". The whole name is tried first, so that qualified
+// names like "dev/lab" are still found. The namespace is empty, if the name
+// holds none.
+func (c *Config) LookupContextNamespace(name string) (*KubeConf, *ContextEntry, string, error) {
+	k, ctx, err := c.LookupContext(name)
+	if !errors.Is(err, ErrNoContext) {
+		return k, ctx, "", err
+	}
+	idx := strings.LastIndex(name, "/")
+	if idx <= 0 || idx == len(name)-1 {
+		return nil, nil, "", err
+	}
+	k, ctx, nsErr := c.LookupContext(name[:idx])
+	if errors.Is(nsErr, ErrNoContext) {
+		return nil, nil, "", err
+	}
+	if nsErr != nil {
+		return nil, nil, "", nsErr
+	}
+	return k, ctx, name[idx+1:], nil
+}
+
+// QualifiedName returns the name of a context prefixed by the alias of the
+// kube config, like "dev/minikube".
+func (k *KubeConf) QualifiedName(name string) string {
+	return k.Alias + "/" + name
+}
+
 // RemoveCurrentContexts removes from every kube config the setting for the
 // currentContext.
 func (c *Config) RemoveCurrentContexts() error {
@@ -194,14 +394,24 @@ func (c *Config) GetKubeConfigBy(path string) *KubeConf {
 	return nil
 }
 
+// GetKubeConfigByAlias returns the KubeConf by a given alias.
+func (c *Config) GetKubeConfigByAlias(alias string) *KubeConf {
+	for _, k := range c.KubeConfs {
+		if k.Alias == alias {
+			return k
+		}
+	}
+	return nil
+}
+
 // GetContextBy returns the context and its index within a single KubeConf given
 // by name.
-func (k *KubeConf) GetContextBy(name string) (map[string]string, int) {
+func (k *KubeConf) GetContextBy(name string) (*ContextEntry, int) {
 	for idx, ctx := range k.Contexts {
-		if ctx["name"] == name {
+		if ctx.Name == name {
 			return ctx, idx
 		}
-		if alias, exists := ctx["alias"]; exists && alias == name {
+		if ctx.Alias != "" && ctx.Alias == name {
 			return ctx, idx
 		}
 	}
@@ -219,82 +429,202 @@ func (k *KubeConf) Exists(name string) bool {
 // Save writes the contexts stored in a KubeConfig into a contexts_<alias>.yaml
 // file.
 func (k *KubeConf) Save() error {
+	unlock, err := k.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	cnf, err := yaml.Marshal(&k.Contexts)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(k.ContextFile, cnf, 0644)
+	return writeFile(k.ContextFile, cnf, FileMode)
+}
+
+// namespaceOf returns the namespace of the context inside the kube config.
+func (k *KubeConf) namespaceOf(contextName string) string {
+	if k == nil || k.KubeConfig == nil {
+		return ""
+	}
+	ctx, _, err := k.KubeConfig.GetContextBy(contextName)
+	if err != nil {
+		return ""
+	}
+	return ctx.Namespace
 }
 
 // UpdateState stores the actual kube config and context under the lastConfig
 // and lastContext inside the .state file. At the same time it updates the
 // values for the current config and current context.
 func (c *Config) UpdateState(k *KubeConf, currentContext string) error {
+	unlock, err := c.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if err := c.GetState(); err != nil {
 		return err
 	}
-	lastConf := c.CurrentConf
-	lastContext := c.CurrentContext
-
-	c.CurrentConf = k.Path
-	c.CurrentContext = currentContext
-
-	c.LastConf = lastConf
-	c.LastContext = lastContext
+	c.State.update(k.Path, currentContext, k.namespaceOf(currentContext))
+	c.State.track(c.Terminal)
+	return c.saveState()
+}
 
+// saveState writes the state into the .state file.
+func (c *Config) saveState() error {
 	cnf, err := yaml.Marshal(&c.State)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(c.Filename, cnf, 0644)
+	return writeFile(c.Filename, cnf, FileMode)
+}
+
+// update moves the current kube config, context and namespace to the last
+// ones and sets the given ones as current. The namespace is remembered for
+// the context.
+func (s *State) update(conf, context, namespace string) {
+	s.LastConf, s.LastContext, s.LastNamespace = s.CurrentConf, s.CurrentContext, s.CurrentNamespace
+	s.CurrentConf, s.CurrentContext, s.CurrentNamespace = conf, context, namespace
+	s.remember(conf, context, namespace)
+	s.push(conf, context, namespace)
+	s.Version = stateVersion
+}
+
+// leave sets the namespace of the current context, which may have changed
+// since the last switch, before the context is left.
+func (s *State) leave(namespace string) {
+	if s.CurrentContext == "" || namespace == "" {
+		return
+	}
+	s.CurrentNamespace = namespace
+	s.remember(s.CurrentConf, s.CurrentContext, namespace)
+	if len(s.History) > 0 && s.History[0].KubeConfig == s.CurrentConf && s.History[0].Context == s.CurrentContext {
+		history := append([]HistoryEntry{}, s.History...)
+		history[0].Namespace = namespace
+		s.History = history
+	}
+}
+
+// remember stores the namespace as the last used one of the context. The
+// map is copied, so that a copy of the state stays unchanged.
+func (s *State) remember(conf, context, namespace string) {
+	if namespace == "" {
+		return
+	}
+	namespaces := make(map[string]map[string]string, len(s.Namespaces)+1)
+	for k, v := range s.Namespaces {
+		namespaces[k] = v
+	}
+	contexts := make(map[string]string, len(namespaces[conf])+1)
+	for k, v := range namespaces[conf] {
+		contexts[k] = v
+	}
+	contexts[context] = namespace
+	namespaces[conf] = contexts
+	s.Namespaces = namespaces
+}
+
+// setOrigins replaces the origins of the contexts of the kube config with
+// the given path. The map is copied, so that a copy of the state stays
+// unchanged.
+func (s *State) setOrigins(conf string, origins map[string]Origin) {
+	all := make(map[string]map[string]Origin, len(s.Origins)+1)
+	for k, v := range s.Origins {
+		all[k] = v
+	}
+	all[conf] = origins
+	s.Origins = all
+}
+
+// Namespace returns the last used namespace of the context of the kube
+// config with the given path.
+func (s *State) Namespace(conf, context string) string {
+	return s.Namespaces[conf][context]
 }
 
 // GetState stores the current state file content into the config. It returns
 // ErrReadStateFile or ErrReadStateFile if the .state file cannot be read or
 // parsed.
 func (c *Config) GetState() error {
-	f, err := os.ReadFile(c.Filename)
+	return c.State.load()
+}
+
+// load reads the content of the state file. A missing file is not an error.
+func (s *State) load() error {
+	f, err := os.ReadFile(s.Filename)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
-		return fmt.Errorf("%w: '%s', err: %w", ErrReadStateFile, c.Filename, err)
+		return fmt.Errorf("%w: '%s', err: %w", ErrReadStateFile, s.Filename, err)
 	}
-	err = yaml.Unmarshal(f, &c.State)
+	// new maps and a new history, so that copies of the state stay unchanged
+	s.Namespaces, s.History, s.Terminals, s.Origins, s.Version = nil, nil, nil, nil, 0
+	err = yaml.Unmarshal(f, s)
 	if err != nil {
-		return fmt.Errorf("%w: '%s', err: %w", ErrParseStateFile, c.Filename, err)
+		return fmt.Errorf("%w: '%s', err: %w", ErrParseStateFile, s.Filename, err)
+	}
+	if err := s.migrate(); err != nil {
+		return fmt.Errorf("%w: '%s', err: %w", ErrParseStateFile, s.Filename, err)
 	}
 	return nil
 }
 
 // CreateListItems is a helper function for the TUI and creates the list of
-// context names and a description.
+// context names and a description. Names, which exist in more than one kube
+// config, are qualified by the alias of the kube config.
 func (c *Config) CreateListItems(filterConfig, filterContext string) []ContextItem {
 	items := []ContextItem{}
+	names := map[string]int{}
+	for _, cnf := range c.KubeConfs {
+		for _, ctx := range cnf.Contexts {
+			names[displayName(ctx)]++
+		}
+	}
 	for _, cnf := range c.KubeConfs {
 		if filterConfig != "" && cnf.Alias != filterConfig {
 			continue
 		}
+		// an invalid template is already rejected by Get
+		tmpl, _ := c.descriptionTemplate(cnf)
+		drifts := map[string]NamespaceDrift{}
+		if cnf.KubeConfig != nil {
+			// a broken contexts file is reported elsewhere
+			found, _ := cnf.NamespaceDrifts()
+			for _, d := range found {
+				drifts[d.Context] = d
+			}
+		}
 		for _, ctx := range cnf.Contexts {
-			name := ctx["name"]
-			if alias, exists := ctx["alias"]; exists {
-				name = alias
+			name := displayName(ctx)
+			if names[name] > 1 {
+				name = cnf.QualifiedName(name)
 			}
 			if filterContext != "" && !strings.Contains(name, filterContext) {
 				continue
 			}
-			descriptions := []string{}
-			for k, v := range ctx {
-				if k != "name" && k != "alias" {
-					descriptions = append(descriptions, fmt.Sprintf("%s: %s", k, v))
-				}
-			}
 			i := ContextItem{
 				Name:        name,
-				Description: strings.Join(descriptions, ", "),
+				Description: c.describe(cnf, tmpl, ctx),
+				Config:      cnf.Alias,
+				Context:     ctx.Name,
+				Protected:   IsProtected(ctx),
+				Fields:      ctx.Strings(),
+			}
+			if d, exists := drifts[ctx.Name]; exists {
+				i.Drift = &d
 			}
 			items = append(items, i)
 		}
 	}
 	return items
 }
+
+// displayName returns the alias of the context or its name, if it has no
+// alias.
+func displayName(ctx *ContextEntry) string {
+	if ctx.Alias != "" {
+		return ctx.Alias
+	}
+	return ctx.Name
+}
@@ -147,14 +147,12 @@ func TestGet(t *testing.T) {
 						Alias:       "t",
 						ContextFile: "contexts.yaml",
 						KubeConfig:  kCnf,
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":       "avap:1",
-								"cluster":     "cluster1",
-								"environment": "prod",
-								"name":        "aws:prod:accountId:us-east-1:cluster1",
-								"namespace":   "monitoring",
-								"region":      "us-east-1",
+								Alias:     "avap:1",
+								Name:      "aws:prod:accountId:us-east-1:cluster1",
+								Namespace: "monitoring",
+								Metadata:  map[string]any{"cluster": "cluster1", "environment": "prod", "region": "us-east-1"},
 							},
 						},
 					},
@@ -231,7 +229,7 @@ func TestKubeConf_SyncNamespaces(t *testing.T) {
 		Path        string
 		Alias       string
 		ContextFile string
-		Contexts    []map[string]string
+		Contexts    []*ContextEntry
 		kubeConfig  *KubeConfig
 	}
 	tests := []struct {
@@ -246,14 +244,12 @@ func TestKubeConf_SyncNamespaces(t *testing.T) {
 				Path:        "testdata/kube.config",
 				Alias:       "",
 				ContextFile: "",
-				Contexts: []map[string]string{
+				Contexts: []*ContextEntry{
 					{
-						"alias":       "avap:1",
-						"cluster":     "cluster1",
-						"environment": "prod",
-						"name":        "aws:prod:accountId:us-east-1:cluster1",
-						"namespace":   "monitoring",
-						"region":      "us-east-1",
+						Alias:     "avap:1",
+						Name:      "aws:prod:accountId:us-east-1:cluster1",
+						Namespace: "monitoring",
+						Metadata:  map[string]any{"cluster": "cluster1", "environment": "prod", "region": "us-east-1"},
 					},
 				},
 				kubeConfig: kubeConfig,
@@ -294,7 +290,7 @@ func TestKubeConf_SyncNamespaces(t *testing.T) {
 
 func TestContextConfig_GetContextBy(t *testing.T) {
 	type fields struct {
-		Contexts []map[string]string
+		Contexts []*ContextEntry
 	}
 	type args struct {
 		name string
@@ -304,16 +300,16 @@ func TestContextConfig_GetContextBy(t *testing.T) {
 		fields fields
 		args   args
 		want   *KubeConf
-		want1  map[string]string
+		want1  *ContextEntry
 		want2  int
 	}{
 		{
 			name: "positive",
 			fields: fields{
-				Contexts: []map[string]string{
-					{"name": "a"},
-					{"name": "b"},
-					{"name": "c"},
+				Contexts: []*ContextEntry{
+					{Name: "a"},
+					{Name: "b"},
+					{Name: "c"},
 				},
 			},
 			args: args{name: "b"},
@@ -321,23 +317,23 @@ func TestContextConfig_GetContextBy(t *testing.T) {
 				Path:        "",
 				Alias:       "",
 				ContextFile: "",
-				Contexts: []map[string]string{
-					{"name": "a"},
-					{"name": "b"},
-					{"name": "c"},
+				Contexts: []*ContextEntry{
+					{Name: "a"},
+					{Name: "b"},
+					{Name: "c"},
 				},
 				KubeConfig: nil,
 			},
-			want1: map[string]string{"name": "b"},
+			want1: &ContextEntry{Name: "b"},
 			want2: 1,
 		},
 		{
 			name: "positive - alias",
 			fields: fields{
-				Contexts: []map[string]string{
-					{"name": "a"},
-					{"name": "e", "alias": "b"},
-					{"name": "c"},
+				Contexts: []*ContextEntry{
+					{Name: "a"},
+					{Name: "e", Alias: "b"},
+					{Name: "c"},
 				},
 			},
 			args: args{name: "b"},
@@ -345,20 +341,20 @@ func TestContextConfig_GetContextBy(t *testing.T) {
 				Path:        "",
 				Alias:       "",
 				ContextFile: "",
-				Contexts: []map[string]string{
-					{"name": "a"},
-					{"name": "e", "alias": "b"},
-					{"name": "c"},
+				Contexts: []*ContextEntry{
+					{Name: "a"},
+					{Name: "e", Alias: "b"},
+					{Name: "c"},
 				},
 				KubeConfig: nil,
 			},
-			want1: map[string]string{"name": "e", "alias": "b"},
+			want1: &ContextEntry{Name: "e", Alias: "b"},
 			want2: 1,
 		},
 		{
 			name: "positive - empty",
 			fields: fields{
-				Contexts: []map[string]string{},
+				Contexts: []*ContextEntry{},
 			},
 			args:  args{name: "b"},
 			want:  nil,
@@ -386,6 +382,94 @@ func TestContextConfig_GetContextBy(t *testing.T) {
 	}
 }
 
+func TestConfig_LookupContext(t *testing.T) {
+	c := &Config{
+		KubeConfs: []*KubeConf{
+			{Alias: "dev", Contexts: []*ContextEntry{{Name: "minikube"}, {Name: "kind", Alias: "k"}}},
+			{Alias: "lab", Contexts: []*ContextEntry{{Name: "minikube"}, {Name: "arn:aws:eks:eu:1:cluster/lab"}}},
+		},
+	}
+	tests := []struct {
+		name        string
+		lookup      string
+		wantConfig  string
+		wantContext string
+		wantErr     error
+	}{
+		{name: "positive - unique name", lookup: "kind", wantConfig: "dev", wantContext: "kind"},
+		{name: "positive - unique alias", lookup: "k", wantConfig: "dev", wantContext: "kind"},
+		{name: "positive - qualified", lookup: "lab/minikube", wantConfig: "lab", wantContext: "minikube"},
+		{name: "positive - qualified alias", lookup: "dev/k", wantConfig: "dev", wantContext: "kind"},
+		{
+			name:        "positive - name with slash",
+			lookup:      "arn:aws:eks:eu:1:cluster/lab",
+			wantConfig:  "lab",
+			wantContext: "arn:aws:eks:eu:1:cluster/lab",
+		},
+		{name: "negative - ambiguous", lookup: "minikube", wantErr: ErrAmbiguousContext},
+		{name: "negative - unknown", lookup: "prod", wantErr: ErrNoContext},
+		{name: "negative - unknown qualified", lookup: "dev/prod", wantErr: ErrNoContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnf, ctx, err := c.LookupContext(tt.lookup)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantConfig, cnf.Alias)
+			assert.Equal(t, tt.wantContext, ctx.Name)
+		})
+	}
+	_, _, err := c.LookupContext("minikube")
+	assert.ErrorContains(t, err, "dev/minikube, lab/minikube")
+}
+
+func TestConfig_LookupContextNamespace(t *testing.T) {
+	c := &Config{
+		KubeConfs: []*KubeConf{
+			{Alias: "dev", Contexts: []*ContextEntry{{Name: "minikube"}, {Name: "kind", Alias: "k"}}},
+			{Alias: "lab", Contexts: []*ContextEntry{{Name: "minikube"}}},
+		},
+	}
+	tests := []struct {
+		name          string
+		lookup        string
+		wantConfig    string
+		wantContext   string
+		wantNamespace string
+		wantErr       error
+	}{
+		{name: "positive - without namespace", lookup: "k", wantConfig: "dev", wantContext: "kind"},
+		{name: "positive - qualified without namespace", lookup: "lab/minikube", wantConfig: "lab", wantContext: "minikube"},
+		{name: "positive - namespace", lookup: "k/web", wantConfig: "dev", wantContext: "kind", wantNamespace: "web"},
+		{
+			name:          "positive - qualified with namespace",
+			lookup:        "lab/minikube/web",
+			wantConfig:    "lab",
+			wantContext:   "minikube",
+			wantNamespace: "web",
+		},
+		{name: "negative - ambiguous with namespace", lookup: "minikube/web", wantErr: ErrAmbiguousContext},
+		{name: "negative - unknown with namespace", lookup: "prod/web", wantErr: ErrNoContext},
+		{name: "negative - empty namespace", lookup: "k/", wantErr: ErrNoContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnf, ctx, ns, err := c.LookupContextNamespace(tt.lookup)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantConfig, cnf.Alias)
+			assert.Equal(t, tt.wantContext, ctx.Name)
+			assert.Equal(t, tt.wantNamespace, ns)
+		})
+	}
+}
+
 func TestConfig_SyncNamespaces(t *testing.T) {
 	kubeConfig := kCnf
 	// to not override the original one
@@ -412,14 +496,12 @@ func TestConfig_SyncNamespaces(t *testing.T) {
 						Path:        "testdata/kube.config",
 						Alias:       "",
 						ContextFile: "",
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":       "avap:1",
-								"cluster":     "cluster1",
-								"environment": "prod",
-								"name":        "aws:prod:accountId:us-east-1:cluster1",
-								"namespace":   "monitoring",
-								"region":      "us-east-1",
+								Alias:     "avap:1",
+								Name:      "aws:prod:accountId:us-east-1:cluster1",
+								Namespace: "monitoring",
+								Metadata:  map[string]any{"cluster": "cluster1", "environment": "prod", "region": "us-east-1"},
 							},
 						},
 						KubeConfig: kubeConfig,
@@ -437,10 +519,10 @@ func TestConfig_SyncNamespaces(t *testing.T) {
 				KubeConfs: []*KubeConf{
 					{
 						Path: "testdata/kube.config",
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":     "no exists",
-								"namespace": "monitoring",
+								Alias:     "no exists",
+								Namespace: "monitoring",
 							},
 						},
 						KubeConfig: kubeConfig,
@@ -536,6 +618,12 @@ func TestConfig_setup(t *testing.T) {
 			}
 			c.setup()
 
+			lock := newDirLock(tt.want.Dir)
+			assert.Equal(t, lock, c.lock)
+			for _, cnf := range c.KubeConfs {
+				assert.Same(t, c.lock, cnf.lock)
+			}
+			tt.want.setLock(c.lock)
 			assert.Equal(t, tt.want, c)
 		})
 	}
@@ -619,7 +707,7 @@ func TestKubeConf_GetContextBy(t *testing.T) {
 		Path        string
 		Alias       string
 		ContextFile string
-		Contexts    []map[string]string
+		Contexts    []*ContextEntry
 		KubeConfig  *KubeConfig
 	}
 	type args struct {
@@ -629,32 +717,32 @@ func TestKubeConf_GetContextBy(t *testing.T) {
 		name   string
 		fields fields
 		args   args
-		want   map[string]string
+		want   *ContextEntry
 		want1  int
 	}{
 		{
 			name: "alias",
 			fields: fields{
-				Contexts: []map[string]string{
+				Contexts: []*ContextEntry{
 					{
-						"alias":     "alias1",
-						"name":      "aws:prod:accountId:us-east-1:cluster1",
-						"namespace": "monitoring",
+						Alias:     "alias1",
+						Name:      "aws:prod:accountId:us-east-1:cluster1",
+						Namespace: "monitoring",
 					},
 					{
-						"alias":     "alias2",
-						"name":      "aws:dev:accountId:us-east-1:cluster2",
-						"namespace": "default",
+						Alias:     "alias2",
+						Name:      "aws:dev:accountId:us-east-1:cluster2",
+						Namespace: "default",
 					},
 				},
 			},
 			args: args{
 				name: "alias2",
 			},
-			want: map[string]string{
-				"alias":     "alias2",
-				"name":      "aws:dev:accountId:us-east-1:cluster2",
-				"namespace": "default",
+			want: &ContextEntry{
+				Alias:     "alias2",
+				Name:      "aws:dev:accountId:us-east-1:cluster2",
+				Namespace: "default",
 			},
 			want1: 1,
 		},
@@ -792,30 +880,30 @@ func TestConfig_CreateListItems(t *testing.T) {
 			fields: fields{
 				KubeConfs: []*KubeConf{
 					{
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":     "alias1",
-								"name":      "aws:prod:accountId:us-east-1:cluster1",
-								"namespace": "monitoring",
+								Alias:     "alias1",
+								Name:      "aws:prod:accountId:us-east-1:cluster1",
+								Namespace: "monitoring",
 							},
 							{
-								"alias":     "alias2",
-								"name":      "aws:dev:accountId:us-east-1:cluster2",
-								"namespace": "default",
+								Alias:     "alias2",
+								Name:      "aws:dev:accountId:us-east-1:cluster2",
+								Namespace: "default",
 							},
 						},
 					},
 					{
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":     "alias3",
-								"name":      "aws:prod:accountId:us-east-1:cluster3",
-								"namespace": "monitoring",
+								Alias:     "alias3",
+								Name:      "aws:prod:accountId:us-east-1:cluster3",
+								Namespace: "monitoring",
 							},
 							{
-								"alias":     "alias4",
-								"name":      "aws:dev:accountId:us-east-1:cluster4",
-								"namespace": "default",
+								Alias:     "alias4",
+								Name:      "aws:dev:accountId:us-east-1:cluster4",
+								Namespace: "default",
 							},
 						},
 					},
@@ -826,8 +914,22 @@ func TestConfig_CreateListItems(t *testing.T) {
 				filterContext: "",
 			},
 			want: []ContextItem{
-				{Name: "alias1", Description: "namespace: monitoring"}, {Name: "alias2", Description: "namespace: default"},
-				{Name: "alias3", Description: "namespace: monitoring"}, {Name: "alias4", Description: "namespace: default"},
+				{
+					Name: "alias1", Description: "namespace: monitoring", Context: "aws:prod:accountId:us-east-1:cluster1",
+					Fields: map[string]string{"alias": "alias1", "name": "aws:prod:accountId:us-east-1:cluster1", "namespace": "monitoring"},
+				},
+				{
+					Name: "alias2", Description: "namespace: default", Context: "aws:dev:accountId:us-east-1:cluster2",
+					Fields: map[string]string{"alias": "alias2", "name": "aws:dev:accountId:us-east-1:cluster2", "namespace": "default"},
+				},
+				{
+					Name: "alias3", Description: "namespace: monitoring", Context: "aws:prod:accountId:us-east-1:cluster3",
+					Fields: map[string]string{"alias": "alias3", "name": "aws:prod:accountId:us-east-1:cluster3", "namespace": "monitoring"},
+				},
+				{
+					Name: "alias4", Description: "namespace: default", Context: "aws:dev:accountId:us-east-1:cluster4",
+					Fields: map[string]string{"alias": "alias4", "name": "aws:dev:accountId:us-east-1:cluster4", "namespace": "default"},
+				},
 			},
 		},
 		{
@@ -836,31 +938,31 @@ func TestConfig_CreateListItems(t *testing.T) {
 				KubeConfs: []*KubeConf{
 					{
 						Alias: "t",
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":     "alias1",
-								"name":      "aws:prod:accountId:us-east-1:cluster1",
-								"namespace": "monitoring",
+								Alias:     "alias1",
+								Name:      "aws:prod:accountId:us-east-1:cluster1",
+								Namespace: "monitoring",
 							},
 							{
-								"alias":     "alias2",
-								"name":      "aws:dev:accountId:us-east-1:cluster2",
-								"namespace": "default",
+								Alias:     "alias2",
+								Name:      "aws:dev:accountId:us-east-1:cluster2",
+								Namespace: "default",
 							},
 						},
 					},
 					{
 						Alias: "x",
-						Contexts: []map[string]string{
+						Contexts: []*ContextEntry{
 							{
-								"alias":     "alias3",
-								"name":      "aws:prod:accountId:us-east-1:cluster3",
-								"namespace": "monitoring",
+								Alias:     "alias3",
+								Name:      "aws:prod:accountId:us-east-1:cluster3",
+								Namespace: "monitoring",
 							},
 							{
-								"alias":     "alias4",
-								"name":      "aws:dev:accountId:us-east-1:cluster4",
-								"namespace": "default",
+								Alias:     "alias4",
+								Name:      "aws:dev:accountId:us-east-1:cluster4",
+								Namespace: "default",
 							},
 						},
 					},
@@ -871,7 +973,54 @@ func TestConfig_CreateListItems(t *testing.T) {
 				filterContext: "alias2",
 			},
 			want: []ContextItem{
-				{Name: "alias2", Description: "namespace: default"},
+				{
+					Name: "alias2", Description: "namespace: default", Config: "t", Context: "aws:dev:accountId:us-east-1:cluster2",
+					Fields: map[string]string{"alias": "alias2", "name": "aws:dev:accountId:us-east-1:cluster2", "namespace": "default"},
+				},
+			},
+		},
+		{
+			name: "positive - qualified duplicates",
+			fields: fields{
+				KubeConfs: []*KubeConf{
+					{Alias: "dev", Contexts: []*ContextEntry{{Name: "minikube"}, {Name: "kind"}}},
+					{Alias: "lab", Contexts: []*ContextEntry{{Name: "minikube"}}},
+				},
+			},
+			want: []ContextItem{
+				{
+					Name: "dev/minikube", Description: "", Config: "dev", Context: "minikube",
+					Fields: map[string]string{"name": "minikube"},
+				},
+				{
+					Name: "kind", Description: "", Config: "dev", Context: "kind",
+					Fields: map[string]string{"name": "kind"},
+				},
+				{
+					Name: "lab/minikube", Description: "", Config: "lab", Context: "minikube",
+					Fields: map[string]string{"name": "minikube"},
+				},
+			},
+		},
+		{
+			name: "positive - typed metadata",
+			fields: fields{
+				KubeConfs: []*KubeConf{
+					{Alias: "dev", Contexts: []*ContextEntry{
+						{Name: "a", Metadata: map[string]any{"tags": []any{"eu", float64(2)}}},
+						{Name: "b", Metadata: map[string]any{protectedKey: true}},
+					}},
+				},
+			},
+			want: []ContextItem{
+				{
+					Name: "a", Description: "tags: [eu, 2]", Config: "dev", Context: "a",
+					Fields: map[string]string{"name": "a", "tags": "[eu, 2]"},
+				},
+				{
+					Name: "b", Description: "protected: true", Config: "dev", Context: "b", Protected: true,
+					Fields: map[string]string{"name": "b", "protected": "true"},
+				},
 			},
 		},
 	}
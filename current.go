@@ -0,0 +1,116 @@
+package k8sctx
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// StateMismatch is a current context in the .state file, which differs from
+// the current context of the kube configs, e.g. after "kubectl config
+// use-context".
+type StateMismatch struct {
+	// State is the current context as stored in the .state file.
+	State HistoryEntry
+	// Actual is the current context of the kube configs.
+	Actual HistoryEntry
+}
+
+func (m StateMismatch) String() string {
+	return fmt.Sprintf("the .state file says the current context is '%s', but the kube configs use '%s'",
+		orNone(m.State.Context), orNone(m.Actual.Context))
+}
+
+// ActualContext returns the current context of the kube configs. Like kubectl
+// does, the first kube config in the order of the paths with a current
+// context wins. Paths, which are not part of the config, are skipped. Without
+// paths, the order of the config.jsonnet is used. The KubeConf is nil, if no
+// kube config has a current context.
+func (c *Config) ActualContext(paths []string) (*KubeConf, string) {
+	if len(paths) == 0 {
+		for _, k := range c.KubeConfs {
+			paths = append(paths, k.Path)
+		}
+	}
+	for _, p := range paths {
+		k := c.kubeConfigAt(p)
+		if k != nil && k.KubeConfig != nil && k.KubeConfig.CurrentContext != "" {
+			return k, k.KubeConfig.CurrentContext
+		}
+	}
+	return nil, ""
+}
+
+// kubeConfigAt returns the KubeConf with the given path in the config or as
+// path of its kube config file.
+func (c *Config) kubeConfigAt(path string) *KubeConf {
+	if k := c.GetKubeConfigBy(path); k != nil {
+		return k
+	}
+	for _, k := range c.KubeConfs {
+		if k.KubeConfig != nil && filepath.Clean(k.KubeConfig.Path) == filepath.Clean(path) {
+			return k
+		}
+	}
+	return nil
+}
+
+// ReconcileState compares the current context of the .state file with the
+// one of the kube configs (see ActualContext). If they differ, the context of
+// the kube configs is recorded in the .state file as a switch made outside of
+// ktx and the mismatch is returned. A .state file without a current context
+// is updated silently.
+func (c *Config) ReconcileState(paths []string) (*StateMismatch, error) {
+	unlock, err := c.lock.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := c.GetState(); err != nil {
+		return nil, err
+	}
+	m := c.stateMismatch(paths)
+	if m == nil || m.Actual.Context == "" {
+		return visible(m), nil
+	}
+	c.State.update(m.Actual.KubeConfig, m.Actual.Context, m.Actual.Namespace)
+	c.History[0].External = true
+	if err := c.saveState(); err != nil {
+		return nil, err
+	}
+	return visible(m), nil
+}
+
+// CheckState works like ReconcileState, but only returns the mismatch. The
+// .state file is not changed.
+func (c *Config) CheckState(paths []string) (*StateMismatch, error) {
+	if err := c.GetState(); err != nil {
+		return nil, err
+	}
+	return visible(c.stateMismatch(paths)), nil
+}
+
+// stateMismatch returns the current contexts of the loaded state and of the
+// kube configs, if they differ. Actual is empty, if no kube config has a
+// current context.
+func (c *Config) stateMismatch(paths []string) *StateMismatch {
+	k, name := c.ActualContext(paths)
+	if k != nil && k.Path == c.CurrentConf && name == c.State.CurrentContext {
+		return nil
+	}
+	m := &StateMismatch{
+		State: HistoryEntry{KubeConfig: c.CurrentConf, Context: c.State.CurrentContext, Namespace: c.CurrentNamespace},
+	}
+	if k != nil {
+		m.Actual = HistoryEntry{KubeConfig: k.Path, Context: name, Namespace: k.namespaceOf(name)}
+	}
+	return m
+}
+
+// visible returns nil for a mismatch, which is not worth a warning: a state
+// without a current context.
+func visible(m *StateMismatch) *StateMismatch {
+	if m == nil || m.State.Context == "" {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,130 @@
+package k8sctx
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_ReconcileState(t *testing.T) {
+	tests := []struct {
+		name string
+		// current is the current context of the kube configs a, b and c
+		current      map[string]string
+		emptyState   bool
+		paths        []string
+		wantMismatch bool
+		wantState    string
+		wantActual   string
+		wantHistory  []HistoryEntry
+	}{
+		{
+			name:        "positive - in sync",
+			current:     map[string]string{"a": "a1"},
+			wantState:   "a1",
+			wantHistory: nil,
+		},
+		{
+			name:         "positive - changed outside of ktx",
+			current:      map[string]string{"b": "b2"},
+			wantMismatch: true,
+			wantState:    "b2",
+			wantActual:   "b2",
+			wantHistory: []HistoryEntry{
+				{KubeConfig: "b", Context: "b2", External: true},
+				{KubeConfig: "a", Context: "a1"},
+			},
+		},
+		{
+			name:         "positive - first kube config of the paths wins",
+			current:      map[string]string{"a": "a1", "b": "b1"},
+			paths:        []string{"c", "b", "a"},
+			wantMismatch: true,
+			wantState:    "b1",
+			wantActual:   "b1",
+			wantHistory: []HistoryEntry{
+				{KubeConfig: "b", Context: "b1", External: true},
+				{KubeConfig: "a", Context: "a1"},
+			},
+		},
+		{
+			name:         "positive - unknown paths are skipped",
+			current:      map[string]string{"c": "c1"},
+			paths:        []string{"unknown", "c"},
+			wantMismatch: true,
+			wantState:    "c1",
+			wantActual:   "c1",
+			wantHistory: []HistoryEntry{
+				{KubeConfig: "c", Context: "c1", External: true},
+				{KubeConfig: "a", Context: "a1"},
+			},
+		},
+		{
+			name:         "positive - no current context in the kube configs",
+			current:      map[string]string{},
+			wantMismatch: true,
+			wantState:    "a1",
+			wantActual:   "",
+			wantHistory:  nil,
+		},
+		{
+			name:       "positive - empty state is updated silently",
+			current:    map[string]string{"a": "a1"},
+			emptyState: true,
+			wantState:  "a1",
+			wantHistory: []HistoryEntry{
+				{KubeConfig: "a", Context: "a1", External: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := switchSetup(t)
+			if tt.emptyState {
+				if err := os.Remove(c.Filename); err != nil {
+					t.Fatal(err)
+				}
+			}
+			paths := []string{}
+			for _, k := range c.KubeConfs {
+				k.KubeConfig.CurrentContext = tt.current[k.Alias]
+			}
+			for _, p := range tt.paths {
+				paths = append(paths, c.Dir+"/"+p)
+			}
+
+			// CheckState reports the same, but doesn't change the .state file
+			before, _ := os.ReadFile(c.Filename)
+			checked, err := c.CheckState(paths)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMismatch, checked != nil, "CheckState")
+			after, _ := os.ReadFile(c.Filename)
+			assert.Equal(t, string(before), string(after), "CheckState changed the .state file")
+
+			m, err := c.ReconcileState(paths)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMismatch, m != nil)
+			if m != nil {
+				assert.Equal(t, "a1", m.State.Context)
+				assert.Equal(t, tt.wantActual, m.Actual.Context)
+			}
+
+			got := &State{Filename: c.Filename}
+			assert.NoError(t, got.load())
+			assert.Equal(t, tt.wantState, got.CurrentContext)
+			if tt.wantHistory == nil {
+				return
+			}
+			assert.Len(t, got.History, len(tt.wantHistory))
+			for idx, want := range tt.wantHistory {
+				if idx >= len(got.History) {
+					break
+				}
+				assert.Equal(t, c.Dir+"/"+want.KubeConfig, got.History[idx].KubeConfig)
+				assert.Equal(t, want.Context, got.History[idx].Context)
+				assert.Equal(t, want.External, got.History[idx].External)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package k8sctx
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// hiddenFields are never shown in the description of a context.
+var hiddenFields = []string{"name", "alias"}
+
+// descriptionTemplate returns the parsed description template of the kube
+// config or, if it has none, the global one. It is nil without a template.
+func (c *Config) descriptionTemplate(k *KubeConf) (*template.Template, error) {
+	text := k.Description
+	if text == "" {
+		text = c.Description
+	}
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(k.Alias).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("description of '%s': %w", k.Alias, err)
+	}
+	return tmpl, nil
+}
+
+// describe returns the description line of the context. With a template,
+// the fields of the context are passed to it as strings, like
+// "{{.environment}} · ns={{.namespace}}". Without a template, the fields are
+// listed like "namespace: web, environment: prod": the namespace first and
+// the others sorted by name. The hidden fields of the global config and the
+// kube config are left out.
+func (c *Config) describe(k *KubeConf, tmpl *template.Template, ctx *ContextEntry) string {
+	fields := ctx.Fields()
+	if tmpl != nil {
+		out := &strings.Builder{}
+		if err := tmpl.Execute(out, ctx.Strings()); err != nil {
+			return fmt.Sprintf("invalid description template: %v", err)
+		}
+		return out.String()
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if !slices.Contains(hiddenFields, key) && !slices.Contains(c.HideFields, key) &&
+			!slices.Contains(k.HideFields, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == "namespace") != (keys[j] == "namespace") {
+			return keys[i] == "namespace"
+		}
+		return keys[i] < keys[j]
+	})
+	descriptions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", key, render(fields[key])))
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,69 @@
+package k8sctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_describe(t *testing.T) {
+	ctx := &ContextEntry{
+		Name:      "arn:lab",
+		Alias:     "lab",
+		Namespace: "web",
+		Metadata: map[string]any{
+			"region":      "eu-central-1",
+			"environment": "dev",
+			"tags":        []any{"eu", "lab"},
+		},
+	}
+	tests := []struct {
+		name     string
+		config   *Config
+		kubeConf *KubeConf
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "positive - stable order with namespace first",
+			config:   &Config{},
+			kubeConf: &KubeConf{},
+			want:     "namespace: web, environment: dev, region: eu-central-1, tags: [eu, lab]",
+		},
+		{
+			name:     "positive - hidden fields",
+			config:   &Config{HideFields: []string{"tags"}},
+			kubeConf: &KubeConf{HideFields: []string{"namespace"}},
+			want:     "environment: dev, region: eu-central-1",
+		},
+		{
+			name:     "positive - global template",
+			config:   &Config{Description: "{{.environment}} · {{.region}} · ns={{.namespace}}"},
+			kubeConf: &KubeConf{},
+			want:     "dev · eu-central-1 · ns=web",
+		},
+		{
+			name:     "positive - template of the kube config wins",
+			config:   &Config{Description: "{{.environment}}"},
+			kubeConf: &KubeConf{Description: "{{.tags}}{{.missing}}"},
+			want:     "[eu, lab]",
+		},
+		{
+			name:     "negative - invalid template",
+			config:   &Config{},
+			kubeConf: &KubeConf{Alias: "x", Description: "{{.environment"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := tt.config.descriptionTemplate(tt.kubeConf)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "description of 'x'")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, tt.config.describe(tt.kubeConf, tmpl, ctx))
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package k8sctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// errNoPatch is returned by the editor if a change cannot be applied in place
+// to the original file content.
+var errNoPatch = errors.New("cannot patch document in place")
+
+type (
+	// edit replaces the bytes between off and end of the source with text.
+	edit struct {
+		off  int
+		end  int
+		text string
+	}
+	// editor collects in place changes for a parsed kube config document. Every
+	// change is applied to the node tree as well as to the original content.
+	// This way only the changed values are touched in the file and the
+	// comments, key order and formatting stay as they are. If a change cannot
+	// be expressed as in place edit, the updated node tree is encoded instead.
+	editor struct {
+		src   []byte
+		top   *yaml.Node
+		lines []int
+		json  bool
+		nl    string
+		edits []edit
+		// patchable is false once a change was made, which cannot be written
+		// back as in place edit.
+		patchable bool
+	}
+)
+
+func newEditor(src []byte, doc *yaml.Node) *editor {
+	e := &editor{src: src, top: root(doc), json: isJSON(src), nl: "\n", patchable: true, lines: []int{0}}
+	if bytes.Contains(src, []byte("\r\n")) {
+		e.nl = "\r\n"
+	}
+	for i, b := range src {
+		if b == '\n' {
+			e.lines = append(e.lines, i+1)
+		}
+	}
+	return e
+}
+
+// isJSON returns true if the content is a JSON document.
+func isJSON(src []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(src), []byte("{"))
+}
+
+// root returns the top level mapping of a document.
+func root(doc *yaml.Node) *yaml.Node {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	if n := doc.Content[0]; n.Kind == yaml.MappingNode {
+		return n
+	}
+	return nil
+}
+
+// lookup returns the key and value node of a mapping for the given key.
+func lookup(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// findContext returns the "context" mapping of the named context inside the
+// "contexts" sequence.
+func findContext(contexts *yaml.Node, name string) *yaml.Node {
+	if contexts == nil || contexts.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, c := range contexts.Content {
+		if _, n := lookup(c, "name"); n != nil && n.Value == name {
+			_, ctx := lookup(c, "context")
+			return ctx
+		}
+	}
+	return nil
+}
+
+// offset converts the line and column (both starting at 1) of a node into
+// the byte offset in the source.
+func (e *editor) offset(n *yaml.Node) (int, bool) {
+	if n.Line < 1 || n.Line > len(e.lines) {
+		return 0, false
+	}
+	off := e.lines[n.Line-1]
+	for col := 1; col < n.Column; col++ {
+		if off >= len(e.src) {
+			return 0, false
+		}
+		_, size := utf8.DecodeRune(e.src[off:])
+		off += size
+	}
+	return off, true
+}
+
+// span returns the byte range of a scalar node in the source.
+func (e *editor) span(n *yaml.Node) (int, int, bool) {
+	off, ok := e.offset(n)
+	if !ok || n.Kind != yaml.ScalarNode {
+		return 0, 0, false
+	}
+	switch n.Style {
+	case 0:
+		if n.Value == "" {
+			// implicit null value like "current-context:"
+			return off, off, true
+		}
+		if !bytes.HasPrefix(e.src[off:], []byte(n.Value)) {
+			return 0, 0, false
+		}
+		return off, off + len(n.Value), true
+	case yaml.DoubleQuotedStyle:
+		for i := off + 1; i < len(e.src); i++ {
+			switch e.src[i] {
+			case '\\':
+				i++
+			case '"':
+				return off, i + 1, true
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := off + 1; i < len(e.src); i++ {
+			if e.src[i] != '\'' {
+				continue
+			}
+			if i+1 < len(e.src) && e.src[i+1] == '\'' {
+				i++
+				continue
+			}
+			return off, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// scalar formats a string value for the given style of the document.
+func (e *editor) scalar(value string, style yaml.Style) string {
+	if e.json || style == yaml.DoubleQuotedStyle || strings.ContainsAny(value, "\r\n") {
+		out, _ := json.Marshal(value)
+		return string(out)
+	}
+	if style == yaml.SingleQuotedStyle {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		out, _ = json.Marshal(value)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (e *editor) key(key string) string {
+	if e.json {
+		return e.scalar(key, yaml.DoubleQuotedStyle)
+	}
+	return key
+}
+
+// set updates the value of key inside the mapping m or adds the key if it
+// does not exist.
+func (e *editor) set(m *yaml.Node, key, value string) {
+	k, v := lookup(m, key)
+	if k != nil {
+		if v.Kind == yaml.ScalarNode && v.Value == value && (v.Tag == "!!str" || value == "" && v.Tag == "!!null") {
+			return
+		}
+		e.replace(v, value)
+		return
+	}
+	e.insert(m, key, value)
+}
+
+func (e *editor) replace(v *yaml.Node, value string) {
+	off, end, ok := e.span(v)
+	style := v.Style
+	if v.Value == "" {
+		// an empty value is usually quoted, which is not needed for the new one
+		style = 0
+	}
+	*v = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: v.Style, Line: v.Line, Column: v.Column}
+	if !ok {
+		e.patchable = false
+		return
+	}
+	text := e.scalar(value, style)
+	if off == end {
+		text = " " + text
+	}
+	e.edits = append(e.edits, edit{off: off, end: end, text: text})
+}
+
+func (e *editor) insert(m *yaml.Node, key, value string) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		e.patchable = false
+		return
+	}
+	defer func() {
+		m.Content = append(m.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+		)
+	}()
+	entry := e.key(key) + ": " + e.scalar(value, 0)
+	if m.Style&yaml.FlowStyle != 0 {
+		e.insertFlow(m, entry)
+		return
+	}
+	e.insertBlock(m, key, entry)
+}
+
+// insertFlow adds the entry as first element into a flow mapping like
+// '{"cluster": "a"}'.
+func (e *editor) insertFlow(m *yaml.Node, entry string) {
+	start, ok := e.offset(m)
+	if !ok || start >= len(e.src) || e.src[start] != '{' {
+		e.patchable = false
+		return
+	}
+	if len(m.Content) == 0 {
+		end := bytes.IndexByte(e.src[start:], '}')
+		if end == -1 || strings.TrimSpace(string(e.src[start+1:start+end])) != "" {
+			e.patchable = false
+			return
+		}
+		e.edits = append(e.edits, edit{off: start + 1, end: start + 1, text: entry})
+		return
+	}
+	first, ok := e.offset(m.Content[0])
+	if !ok || first <= start {
+		e.patchable = false
+		return
+	}
+	sep := string(e.src[start+1 : first])
+	if strings.TrimSpace(sep) != "" {
+		e.patchable = false
+		return
+	}
+	if !strings.Contains(sep, "\n") {
+		sep = " "
+	}
+	e.edits = append(e.edits, edit{off: first, end: first, text: entry + "," + sep})
+}
+
+// insertBlock adds the entry as new line into a block mapping. The entry is
+// placed in alphabetical order, like kubectl does, if possible. Entries for
+// the top level mapping are appended to the end of the file.
+func (e *editor) insertBlock(m *yaml.Node, key, entry string) {
+	if len(m.Content) == 0 {
+		e.patchable = false
+		return
+	}
+	if m == e.top {
+		text := entry + e.nl
+		if len(e.src) > 0 && !bytes.HasSuffix(e.src, []byte("\n")) {
+			text = e.nl + text
+		}
+		e.edits = append(e.edits, edit{off: len(e.src), end: len(e.src), text: text})
+		return
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value > key && e.insertBefore(m.Content[i], entry) {
+			return
+		}
+	}
+	k, v := m.Content[len(m.Content)-2], m.Content[len(m.Content)-1]
+	if _, end, ok := e.span(v); ok && v.Line == k.Line {
+		if eol := bytes.IndexByte(e.src[end:], '\n'); eol != -1 {
+			off := end + eol + 1
+			text := strings.Repeat(" ", k.Column-1) + entry + e.nl
+			e.edits = append(e.edits, edit{off: off, end: off, text: text})
+			return
+		}
+	}
+	if !e.insertBefore(m.Content[0], entry) {
+		e.patchable = false
+	}
+}
+
+// insertBefore adds the entry as new line above the line of the key node k,
+// if k is the first element in its line.
+func (e *editor) insertBefore(k *yaml.Node, entry string) bool {
+	off, ok := e.offset(k)
+	if !ok {
+		return false
+	}
+	start := e.lines[k.Line-1]
+	indent := string(e.src[start:off])
+	if strings.TrimSpace(indent) != "" {
+		return false
+	}
+	e.edits = append(e.edits, edit{off: start, end: start, text: indent + entry + e.nl})
+	return true
+}
+
+// apply returns the source with all edits applied.
+func (e *editor) apply() ([]byte, error) {
+	if !e.patchable {
+		return nil, errNoPatch
+	}
+	edits := append([]edit{}, e.edits...)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].off > edits[j].off })
+	out := append([]byte{}, e.src...)
+	for _, ed := range edits {
+		out = append(out[:ed.off], append([]byte(ed.text), out[ed.end:]...)...)
+	}
+	return out, nil
+}
+
+// encode returns the node tree in the format of the source. It is used if
+// the changes cannot be applied in place.
+func (e *editor) encode(doc *yaml.Node) ([]byte, error) {
+	if e.json {
+		var b bytes.Buffer
+		encodeJSON(&b, doc, indentOf(e.src, "  "), "")
+		b.WriteString(e.nl)
+		return b.Bytes(), nil
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(len(indentOf(e.src, "    ")))
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// indentOf returns the indentation of the first indented line in the source.
+func indentOf(src []byte, fallback string) string {
+	for _, line := range strings.Split(string(src), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return fallback
+}
+
+// encodeJSON writes the node tree as indented JSON and keeps the key order.
+func encodeJSON(b *bytes.Buffer, n *yaml.Node, indent, prefix string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) > 0 {
+			encodeJSON(b, n.Content[0], indent, prefix)
+		}
+	case yaml.AliasNode:
+		encodeJSON(b, n.Alias, indent, prefix)
+	case yaml.MappingNode:
+		if len(n.Content) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, _ := json.Marshal(n.Content[i].Value)
+			fmt.Fprintf(b, "%s%s%s: ", prefix, indent, key)
+			encodeJSON(b, n.Content[i+1], indent, prefix+indent)
+			if i+2 < len(n.Content) {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(prefix + "}")
+	case yaml.SequenceNode:
+		if len(n.Content) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, c := range n.Content {
+			b.WriteString(prefix + indent)
+			encodeJSON(b, c, indent, prefix+indent)
+			if i+1 < len(n.Content) {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(prefix + "]")
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!null":
+			b.WriteString("null")
+		case "!!bool", "!!int", "!!float":
+			if json.Valid([]byte(n.Value)) {
+				b.WriteString(n.Value)
+				return
+			}
+			fallthrough
+		default:
+			out, _ := json.Marshal(n.Value)
+			b.Write(out)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package k8sctx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	kubectlConfig = `apiVersion: v1
+# clusters managed by the cloud cli
+clusters:
+- cluster:
+    certificate-authority-data: abcd
+    server: https://dev.example.com
+  name: dev
+- cluster:
+    server: https://prod.example.com
+  name: prod
+contexts:
+- context:
+    cluster: dev
+    extensions:
+    - extension:
+        last-update: Mon, 01 Jan 2024
+      name: context_info
+    user: dev
+  name: dev
+- context:
+    cluster: prod
+    namespace: default # keep me
+    user: prod
+  name: prod
+current-context: dev
+kind: Config
+preferences: {}
+users:
+- name: dev
+  user:
+    token: secret
+- name: prod
+  user:
+    token: secret
+extensions:
+- name: some-tool
+  extension: {enabled: true}
+`
+	jsonConfig = `{
+  "kind": "Config",
+  "apiVersion": "v1",
+  "preferences": {},
+  "clusters": [{"name": "dev", "cluster": {"server": "https://dev.example.com"}}],
+  "users": [{"name": "dev", "user": {"token": "secret"}}],
+  "contexts": [
+    {
+      "name": "dev",
+      "context": {
+        "cluster": "dev",
+        "user": "dev"
+      }
+    }
+  ],
+  "current-context": ""
+}
+`
+)
+
+func TestKubeConfig_SaveContexts_lossless(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		contextName string
+		namespace   string
+	}
+	tests := []struct {
+		name    string
+		content string
+		args    args
+		want    string
+	}{
+		{
+			name:    "switch current context",
+			content: kubectlConfig,
+			args:    args{contextName: "prod"},
+			want: replace(kubectlConfig,
+				"current-context: dev\n", "current-context: prod\n",
+			),
+		},
+		{
+			name:    "add namespace in alphabetical order",
+			content: kubectlConfig,
+			args:    args{contextName: "dev", namespace: "monitoring"},
+			want: replace(kubectlConfig,
+				"      name: context_info\n    user: dev\n",
+				"      name: context_info\n    namespace: monitoring\n    user: dev\n",
+			),
+		},
+		{
+			name:    "update namespace and keep comment",
+			content: kubectlConfig,
+			args:    args{contextName: "prod", namespace: "monitoring"},
+			want: replace(kubectlConfig,
+				"current-context: dev\n", "current-context: prod\n",
+				"namespace: default # keep me", "namespace: monitoring # keep me",
+			),
+		},
+		{
+			name:    "missing current context",
+			content: "apiVersion: v1\ncontexts:\n- name: dev\n  context:\n    cluster: dev\n    user: dev\n",
+			args:    args{contextName: "dev"},
+			want:    "apiVersion: v1\ncontexts:\n- name: dev\n  context:\n    cluster: dev\n    user: dev\ncurrent-context: dev\n",
+		},
+		{
+			name:    "empty current context",
+			content: "contexts:\n- name: dev\n  context: {cluster: dev, user: dev}\ncurrent-context: \"\"\n",
+			args:    args{contextName: "dev", namespace: "kube-system"},
+			want:    "contexts:\n- name: dev\n  context: {namespace: kube-system, cluster: dev, user: dev}\ncurrent-context: dev\n",
+		},
+		{
+			name:    "json stays json",
+			content: jsonConfig,
+			args:    args{contextName: "dev", namespace: "monitoring"},
+			want: replace(jsonConfig,
+				`"current-context": ""`, `"current-context": "dev"`,
+				"        \"cluster\": \"dev\",\n", "        \"namespace\": \"monitoring\",\n        \"cluster\": \"dev\",\n",
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			k, err := GetKubeConfig(path)
+			if err != nil {
+				t.Fatalf("GetKubeConfig() error = %v", err)
+			}
+			if err := k.AddNamespaceTo(tt.args.contextName, tt.args.namespace); err != nil {
+				t.Fatalf("KubeConfig.AddNamespaceTo() error = %v", err)
+			}
+			if err := k.SetContextTo(tt.args.contextName); err != nil {
+				t.Fatalf("KubeConfig.SetContextTo() error = %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_encodeJSON(t *testing.T) {
+	t.Parallel()
+	k, err := GetKubeConfig("testdata/kube.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newEditor([]byte(jsonConfig), k.doc)
+	got, err := e.encode(k.doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, isJSON(got))
+	j := &KubeConfig{}
+	if err := yaml.Unmarshal(got, j); err != nil {
+		t.Fatalf("encoded JSON cannot be parsed: %v", err)
+	}
+	assert.Equal(t, k.Contexts, j.Contexts)
+	assert.Equal(t, k.CurrentContext, j.CurrentContext)
+}
+
+// replace applies the old/new string pairs to s.
+func replace(s string, oldnew ...string) string {
+	for i := 0; i+1 < len(oldnew); i += 2 {
+		if !strings.Contains(s, oldnew[i]) {
+			panic("test setup: " + oldnew[i] + " not found")
+		}
+		s = strings.Replace(s, oldnew[i], oldnew[i+1], 1)
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policies for a namespace drift, which is a context with different
+// namespaces in the kube config and the config.jsonnet.
+const (
+	// DriftConfigWins writes the namespace of the config.jsonnet into the
+	// kube config.
+	DriftConfigWins = "config"
+	// DriftKubeConfigWins writes the namespace of the kube config into the
+	// contexts file.
+	DriftKubeConfigWins = "kubeconfig"
+	// DriftAsk changes nothing; the drift is reported and must be resolved
+	// by the user, e.g. via "ktx sync".
+	DriftAsk = "ask"
+)
+
+var (
+	// ErrDriftPolicy is returned for an unknown "namespace_drift" setting.
+	ErrDriftPolicy = errors.New("unknown value for namespace_drift")
+	// ErrForcedNamespace is returned if the namespace of the kube config
+	// should win, but the namespace is set by the config.jsonnet itself.
+	ErrForcedNamespace = errors.New("namespace is set by the config.jsonnet")
+)
+
+// NamespaceDrift holds the namespaces of a context, which differ between the
+// kube config and the config.jsonnet.
+type NamespaceDrift struct {
+	Context string `json:"context"`
+	// KubeConfig is the namespace inside the kube config.
+	KubeConfig string `json:"kubeconfig"`
+	// ContextFile is the namespace inside the contexts file.
+	ContextFile string `json:"contextFile"`
+	// Config is the namespace as evaluated by the config.jsonnet.
+	Config string `json:"config"`
+}
+
+func (d NamespaceDrift) String() string {
+	return fmt.Sprintf("namespace drift of '%s': kubeconfig: %s, contexts file: %s, config: %s",
+		d.Context, orNone(d.KubeConfig), orNone(d.ContextFile), orNone(d.Config))
+}
+
+// Forced returns true if the config.jsonnet overrides a namespace of the
+// contexts file. In this case the drift cannot be resolved by writing the
+// namespace of the kube config into the contexts file. A namespace, which the
+// config.jsonnet only adds to a context without one in the contexts file (like
+// the "default" of the generated template), is not forced.
+func (d NamespaceDrift) Forced() bool {
+	return d.ContextFile != "" && d.ContextFile != d.Config
+}
+
+// validDrift returns ErrDriftPolicy for an unknown drift policy. An empty
+// policy lets the kube config win, but fills in the namespace of the
+// config.jsonnet for a context without one in the kube config.
+func validDrift(policy string) error {
+	switch policy {
+	case "", DriftConfigWins, DriftKubeConfigWins, DriftAsk:
+		return nil
+	}
+	return fmt.Errorf("%w: '%s', use '%s', '%s' or '%s'",
+		ErrDriftPolicy, policy, DriftConfigWins, DriftKubeConfigWins, DriftAsk)
+}
+
+// NamespaceDrifts returns the contexts with a namespace in the config.jsonnet,
+// which differs from the one in the kube config. Stale contexts are skipped.
+func (k *KubeConf) NamespaceDrifts() ([]NamespaceDrift, error) {
+	changes, err := k.namespaceChanges()
+	if err != nil || len(changes) == 0 {
+		return []NamespaceDrift{}, err
+	}
+	file, err := k.fileNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	drifts := make([]NamespaceDrift, 0, len(changes))
+	for _, ch := range changes {
+		drifts = append(drifts, NamespaceDrift{
+			Context:     ch.Context,
+			KubeConfig:  ch.From,
+			ContextFile: file[ch.Context],
+			Config:      ch.To,
+		})
+	}
+	return drifts, nil
+}
+
+// fileNamespaces returns the namespaces of the contexts as written in the
+// contexts file. A missing file has no namespaces.
+func (k *KubeConf) fileNamespaces() (map[string]string, error) {
+	namespaces := map[string]string{}
+	f, err := os.ReadFile(k.ContextFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return namespaces, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contexts := []map[string]interface{}{}
+	if err := yaml.Unmarshal(f, &contexts); err != nil {
+		return nil, fmt.Errorf("%w: '%s', err: %w", ErrParseConfig, k.ContextFile, err)
+	}
+	for _, ctx := range contexts {
+		if ns, exists := ctx["namespace"]; exists && ns != nil {
+			namespaces[fmt.Sprint(ctx["name"])] = fmt.Sprint(ns)
+		}
+	}
+	return namespaces, nil
+}
+
+// ResolveDrift applies the namespace of the winning side: with
+// DriftConfigWins the namespace of the config.jsonnet is written into the
+// kube config, with DriftKubeConfigWins the namespace of the kube config is
+// written into the contexts file.
+func (k *KubeConf) ResolveDrift(d NamespaceDrift, winner string) error {
+	unlock, err := k.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	switch winner {
+	case DriftConfigWins:
+		return k.KubeConfig.AddNamespaceTo(d.Context, d.Config)
+	case DriftKubeConfigWins:
+		if d.Forced() {
+			return fmt.Errorf("%w of '%s'", ErrForcedNamespace, d.Context)
+		}
+		ctx, idx := k.GetContextBy(d.Context)
+		if idx == -1 {
+			return fmt.Errorf("%w with name '%s'", ErrNoContext, d.Context)
+		}
+		ctx.Namespace = d.KubeConfig
+		return k.Save()
+	}
+	return fmt.Errorf("%w: '%s'", ErrDriftPolicy, winner)
+}
@@ -0,0 +1,187 @@
+package k8sctx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// driftSetup returns a config with the kube config "b". The context "b1" has
+// the namespace "dev" in the kube config and "web" in the contexts file. The
+// namespace "forced" of "b2" is set by the config.jsonnet, which overrides the
+// namespace "file" of the contexts file.
+func driftSetup(t *testing.T, policy string) *Config {
+	t.Helper()
+	c, _ := switchSetup(t)
+	kc := c.KubeConfs[1]
+	if err := kc.KubeConfig.AddNamespaceTo("b1", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	kc.ContextFile = filepath.Join(c.Dir, "contexts_b.yaml")
+	file := "- name: b1\n  namespace: web\n- name: b2\n  namespace: file\n"
+	if err := os.WriteFile(kc.ContextFile, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kc.Contexts = []*ContextEntry{
+		{Name: "b1", Namespace: "web"},
+		{Name: "b2", Namespace: "forced"},
+	}
+	c.KubeConfs = []*KubeConf{kc}
+	c.NamespaceDrift = policy
+	return c
+}
+
+func TestKubeConf_NamespaceDrifts(t *testing.T) {
+	c := driftSetup(t, "")
+	got, err := c.KubeConfs[0].NamespaceDrifts()
+	if err != nil {
+		t.Fatalf("KubeConf.NamespaceDrifts() error = %v", err)
+	}
+	assert.Equal(t, []NamespaceDrift{
+		{Context: "b1", KubeConfig: "dev", ContextFile: "web", Config: "web"},
+		{Context: "b2", KubeConfig: "", ContextFile: "file", Config: "forced"},
+	}, got)
+	assert.False(t, got[0].Forced())
+	assert.True(t, got[1].Forced())
+	assert.False(t, NamespaceDrift{KubeConfig: "dev", Config: "default"}.Forced())
+}
+
+func TestConfig_Sync_drift(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		wantNamespaces []NamespaceChange
+		wantFile       []NamespaceChange
+		wantDrifts     []string
+		wantKube       map[string]string
+	}{
+		{
+			name:           "default",
+			policy:         "",
+			wantNamespaces: []NamespaceChange{{Context: "b2", From: "", To: "forced"}},
+			wantFile:       []NamespaceChange{{Context: "b1", From: "web", To: "dev"}},
+			wantKube:       map[string]string{"b1": "dev", "b2": "forced"},
+		},
+		{
+			name:   "config wins",
+			policy: DriftConfigWins,
+			wantNamespaces: []NamespaceChange{
+				{Context: "b1", From: "dev", To: "web"},
+				{Context: "b2", From: "", To: "forced"},
+			},
+			wantKube: map[string]string{"b1": "web", "b2": "forced"},
+		},
+		{
+			name:       "kube config wins",
+			policy:     DriftKubeConfigWins,
+			wantFile:   []NamespaceChange{{Context: "b1", From: "web", To: "dev"}},
+			wantDrifts: []string{"b2"},
+			wantKube:   map[string]string{"b1": "dev", "b2": ""},
+		},
+		{
+			name:       "ask",
+			policy:     DriftAsk,
+			wantDrifts: []string{"b1", "b2"},
+			wantKube:   map[string]string{"b1": "dev", "b2": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := driftSetup(t, tt.policy)
+			reports, err := c.Sync(false)
+			if err != nil {
+				t.Fatalf("Config.Sync() error = %v", err)
+			}
+			r := reports[0]
+			assert.Equal(t, tt.wantNamespaces, r.Namespaces)
+			assert.Equal(t, tt.wantFile, r.FileNamespaces)
+			drifts := []string{}
+			for _, d := range r.Drifts {
+				drifts = append(drifts, d.Context)
+			}
+			assert.ElementsMatch(t, tt.wantDrifts, drifts)
+
+			k, err := GetKubeConfig(c.KubeConfs[0].Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, ns := range tt.wantKube {
+				ctx, _, _ := k.GetContextBy(name)
+				assert.Equal(t, ns, ctx.Namespace, "namespace of %s", name)
+			}
+			file, err := c.KubeConfs[0].fileNamespaces()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFile != nil {
+				assert.Equal(t, "dev", file["b1"])
+			} else {
+				assert.Equal(t, "web", file["b1"])
+			}
+		})
+	}
+}
+
+func TestConfig_Sync_templateNamespace(t *testing.T) {
+	for _, policy := range []string{"", DriftKubeConfigWins} {
+		t.Run(policy, func(t *testing.T) {
+			c := driftSetup(t, policy)
+			kc := c.KubeConfs[0]
+			// the namespace "default" is added by the config.jsonnet
+			if err := os.WriteFile(kc.ContextFile, []byte("- name: b1\n- name: b2\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			kc.Contexts[0].Namespace = "default"
+			kc.Contexts[1].Namespace = ""
+			reports, err := c.Sync(false)
+			if err != nil {
+				t.Fatalf("Config.Sync() error = %v", err)
+			}
+			assert.Empty(t, reports[0].Drifts)
+			assert.Equal(t, []NamespaceChange{{Context: "b1", From: "", To: "dev"}}, reports[0].FileNamespaces)
+			file, err := kc.fileNamespaces()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "dev", file["b1"])
+		})
+	}
+}
+
+func TestKubeConf_ResolveDrift(t *testing.T) {
+	c := driftSetup(t, DriftAsk)
+	kc := c.KubeConfs[0]
+	drifts, err := kc.NamespaceDrifts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.ErrorIs(t, kc.ResolveDrift(drifts[1], DriftKubeConfigWins), ErrForcedNamespace)
+	assert.ErrorIs(t, kc.ResolveDrift(drifts[1], "mine"), ErrDriftPolicy)
+	assert.NoError(t, kc.ResolveDrift(drifts[1], DriftConfigWins))
+	assert.NoError(t, kc.ResolveDrift(drifts[0], DriftKubeConfigWins))
+
+	drifts, err = kc.NamespaceDrifts()
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func Test_validDrift(t *testing.T) {
+	for _, policy := range []string{"", DriftConfigWins, DriftKubeConfigWins, DriftAsk} {
+		assert.NoError(t, validDrift(policy))
+	}
+	assert.ErrorIs(t, validDrift("both"), ErrDriftPolicy)
+}
+
+func TestConfig_CreateListItems_drift(t *testing.T) {
+	c := driftSetup(t, DriftAsk)
+	items := c.CreateListItems("", "")
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, &NamespaceDrift{Context: "b1", KubeConfig: "dev", ContextFile: "web", Config: "web"}, items[0].Drift)
+	}
+	if err := c.KubeConfs[0].KubeConfig.AddNamespaceTo("b1", "web"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, c.CreateListItems("", "b1")[0].Drift)
+}
@@ -0,0 +1,254 @@
+package k8sctx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrContextField is returned for a known field of a context with a value,
+// which is not a string, like "alias: 1".
+var ErrContextField = errors.New("invalid field of context")
+
+// ContextEntry is a context in the contexts file or in the config.jsonnet.
+// Next to the known fields, it holds free-form metadata like "environment" or
+// "tags", which can be any JSON or YAML value, like a number, a bool, a list
+// or an object. In the files, the metadata is written next to the known
+// fields.
+type ContextEntry struct {
+	// Name of the context inside the kube config.
+	Name string
+	// Alias is shown in the list instead of the name, if set.
+	Alias string
+	// Namespace is the default namespace of the context.
+	Namespace string
+	// KubeConfig is the path of the kube config of the context.
+	KubeConfig string
+	// Metadata holds all other fields of the context.
+	Metadata map[string]any
+}
+
+// known returns a pointer to the known field with the given key or nil.
+func (e *ContextEntry) known(key string) *string {
+	switch key {
+	case "name":
+		return &e.Name
+	case "alias":
+		return &e.Alias
+	case "namespace":
+		return &e.Namespace
+	case "kubeconfig":
+		return &e.KubeConfig
+	}
+	return nil
+}
+
+// Field returns the value of the field with the given key as string. Values,
+// which are no strings, are rendered like "true", "1" or "[a, b]". A missing
+// field is empty.
+func (e *ContextEntry) Field(key string) string {
+	if f := e.known(key); f != nil {
+		return *f
+	}
+	return render(e.Metadata[key])
+}
+
+// Has returns true if the context has a non-empty field with the given key.
+func (e *ContextEntry) Has(key string) bool {
+	if f := e.known(key); f != nil {
+		return *f != ""
+	}
+	v, exists := e.Metadata[key]
+	return exists && v != nil
+}
+
+// Bool returns true for a field set to true or to a string like "true".
+func (e *ContextEntry) Bool(key string) bool {
+	switch v := e.Metadata[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+// Values returns the items of a list field. A string is split at the commas.
+func (e *ContextEntry) Values(key string) []string {
+	switch v := e.Metadata[key].(type) {
+	case nil:
+		return splitList(e.Field(key))
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, i := range v {
+			values = append(values, render(i))
+		}
+		return values
+	case string:
+		return splitList(v)
+	default:
+		return []string{render(v)}
+	}
+}
+
+// Set sets the field with the given key. Known fields take the value as
+// string.
+func (e *ContextEntry) Set(key string, value any) {
+	if f := e.known(key); f != nil {
+		*f = render(value)
+		return
+	}
+	if e.Metadata == nil {
+		e.Metadata = map[string]any{}
+	}
+	e.Metadata[key] = value
+}
+
+// Delete removes the field with the given key.
+func (e *ContextEntry) Delete(key string) {
+	if f := e.known(key); f != nil {
+		*f = ""
+		return
+	}
+	delete(e.Metadata, key)
+	if len(e.Metadata) == 0 {
+		e.Metadata = nil
+	}
+}
+
+// Fields returns all fields of the context as written in the files.
+func (e *ContextEntry) Fields() map[string]any {
+	fields := make(map[string]any, len(e.Metadata)+4)
+	for k, v := range e.Metadata {
+		fields[k] = v
+	}
+	for _, key := range []string{"name", "alias", "namespace", "kubeconfig"} {
+		if v := *e.known(key); v != "" || key == "name" {
+			fields[key] = v
+		}
+	}
+	return fields
+}
+
+// Strings returns all fields of the context rendered as strings.
+func (e *ContextEntry) Strings() map[string]string {
+	fields := e.Fields()
+	values := make(map[string]string, len(fields))
+	for k, v := range fields {
+		values[k] = render(v)
+	}
+	return values
+}
+
+// Clone returns a deep copy of the context.
+func (e *ContextEntry) Clone() *ContextEntry {
+	cp := *e
+	cp.Metadata = nil
+	for k, v := range e.Metadata {
+		cp.Set(k, cloneValue(v))
+	}
+	return &cp
+}
+
+func (e ContextEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Fields())
+}
+
+func (e *ContextEntry) UnmarshalJSON(data []byte) error {
+	fields := map[string]any{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	return e.setFields(fields)
+}
+
+func (e ContextEntry) MarshalYAML() (interface{}, error) {
+	return e.Fields(), nil
+}
+
+func (e *ContextEntry) UnmarshalYAML(node *yaml.Node) error {
+	fields := map[string]any{}
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+	return e.setFields(fields)
+}
+
+// setFields replaces the fields of the context. The known fields must be
+// strings.
+func (e *ContextEntry) setFields(fields map[string]any) error {
+	*e = ContextEntry{}
+	for k, v := range fields {
+		f := e.known(k)
+		if f == nil {
+			e.Set(k, v)
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%w '%v': '%s' must be a string, got %s", ErrContextField, fields["name"], k, render(v))
+		}
+		*f = s
+	}
+	return nil
+}
+
+// render returns the value as string: numbers without exponent, lists like
+// "[a, b]" and objects like "{key: value}" with sorted keys.
+func render(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, i := range v {
+			items = append(items, render(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, k := range keys {
+			items = append(items, k+": "+render(v[k]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// cloneValue returns a deep copy of lists and objects.
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case []any:
+		cp := make([]any, len(v))
+		for i, item := range v {
+			cp[i] = cloneValue(item)
+		}
+		return cp
+	case map[string]any:
+		cp := make(map[string]any, len(v))
+		for k, item := range v {
+			cp[k] = cloneValue(item)
+		}
+		return cp
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package k8sctx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestContextEntry_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *ContextEntry
+		wantErr error
+	}{
+		{
+			name: "positive - strings only",
+			data: `{"name": "a", "alias": "prod", "namespace": "web", "environment": "prod"}`,
+			want: &ContextEntry{Name: "a", Alias: "prod", Namespace: "web", Metadata: map[string]any{"environment": "prod"}},
+		},
+		{
+			name: "positive - typed metadata",
+			data: `{"name": "a", "protected": true, "priority": 1, "tags": ["eu", "prod"], "owner": {"team": "sre"}}`,
+			want: &ContextEntry{Name: "a", Metadata: map[string]any{
+				"protected": true,
+				"priority":  float64(1),
+				"tags":      []any{"eu", "prod"},
+				"owner":     map[string]any{"team": "sre"},
+			}},
+		},
+		{
+			name: "positive - null namespace",
+			data: `{"name": "a", "namespace": null}`,
+			want: &ContextEntry{Name: "a"},
+		},
+		{
+			name:    "negative - alias is no string",
+			data:    `{"name": "a", "alias": 1}`,
+			wantErr: ErrContextField,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &ContextEntry{}
+			err := json.Unmarshal([]byte(tt.data), got)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorContains(t, err, "'alias' must be a string, got 1")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestContextEntry_yaml(t *testing.T) {
+	file := `- alias: prod
+  environment: prod
+  kube_cluster: a
+  name: a
+  priority: 1
+  protected: true
+  stale: "true"
+  tags:
+    - eu
+    - prod
+`
+	contexts := []*ContextEntry{}
+	assert.NoError(t, yaml.Unmarshal([]byte(file), &contexts))
+	if !assert.Len(t, contexts, 1) {
+		return
+	}
+	e := contexts[0]
+	assert.Equal(t, "prod", e.Alias)
+	assert.True(t, e.Bool("protected"))
+	assert.True(t, e.Bool("stale"))
+	assert.False(t, e.Bool("environment"))
+	assert.Equal(t, "1", e.Field("priority"))
+	assert.Equal(t, "[eu, prod]", e.Field("tags"))
+	assert.Equal(t, []string{"eu", "prod"}, e.Values("tags"))
+
+	out, err := yaml.Marshal(contexts)
+	assert.NoError(t, err)
+	assert.Equal(t, file, string(out), "round trip changed the file")
+}
+
+func TestContextEntry_fields(t *testing.T) {
+	e := &ContextEntry{Name: "a"}
+	e.Set("namespace", "web")
+	e.Set("namespaces", "default, web")
+	e.Set("owner", map[string]any{"team": "sre", "chat": "#sre"})
+	assert.Equal(t, "web", e.Namespace)
+	assert.True(t, e.Has("namespaces"))
+	assert.Equal(t, []string{"default", "web"}, e.Values("namespaces"))
+	assert.Equal(t, "{chat: #sre, team: sre}", e.Field("owner"))
+
+	cp := e.Clone()
+	cp.Metadata["owner"].(map[string]any)["team"] = "dev"
+	assert.Equal(t, "sre", e.Metadata["owner"].(map[string]any)["team"], "clone shares the metadata")
+
+	e.Delete("namespace")
+	e.Delete("namespaces")
+	e.Delete("owner")
+	assert.Equal(t, &ContextEntry{Name: "a"}, e)
+	assert.False(t, e.Has("namespace"))
+}
@@ -0,0 +1,99 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// FileMode is used for files created by ktx. Existing files keep their
+	// mode.
+	FileMode os.FileMode = 0600
+	// maxLinks limits the number of symlinks followed to find the real file.
+	maxLinks = 255
+)
+
+// ErrWriteFile is returned if a file cannot be written.
+var ErrWriteFile = errors.New("failed to write file")
+
+// writeFile writes the data into the file given by path. It follows symlinks
+// to the real file. The data is first written into a temporary file, which
+// then replaces the original one. This way the file is never left half
+// written. Existing files keep their mode; new ones are created with perm.
+// The caller must hold the lock of the config dir (see Config.Lock), so that
+// concurrent ktx processes don't change the files at the same time.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	target, err := resolveLinks(path)
+	if err != nil {
+		return fmt.Errorf("%w: '%s', err: %w", ErrWriteFile, path, err)
+	}
+	if err := replaceFile(target, data, perm); err != nil {
+		return fmt.Errorf("%w: '%s', err: %w", ErrWriteFile, path, err)
+	}
+	return nil
+}
+
+// resolveLinks returns the path of the file a (chain of) symlink(s) points
+// to. The path itself is returned if it is not a symlink or doesn't exist.
+func resolveLinks(path string) (string, error) {
+	for i := 0; i < maxLinks; i++ {
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+	return "", fmt.Errorf("too many links for '%s'", path)
+}
+
+// replaceFile writes the data into a temporary file next to path and renames
+// it afterwards to path.
+func replaceFile(path string, data []byte, perm os.FileMode) (err error) {
+	mode := perm
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
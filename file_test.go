@@ -0,0 +1,141 @@
+package k8sctx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_writeFile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		existing os.FileMode
+		link     bool
+		wantMode os.FileMode
+	}{
+		{
+			name:     "new file",
+			wantMode: FileMode,
+		},
+		{
+			name:     "keep mode of existing file",
+			existing: 0640,
+			wantMode: 0640,
+		},
+		{
+			name:     "follow symlink",
+			existing: 0600,
+			link:     true,
+			wantMode: 0600,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			target := filepath.Join(dir, "config")
+			path := target
+			if tt.existing != 0 {
+				if err := os.WriteFile(target, []byte("old"), tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(target, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.link {
+				path = filepath.Join(dir, "link")
+				if err := os.Symlink("config", path); err != nil {
+					t.Skipf("symlinks not supported: %v", err)
+				}
+			}
+			if err := writeFile(path, []byte("new"), FileMode); err != nil {
+				t.Fatalf("writeFile() error = %v", err)
+			}
+			got, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "new", string(got))
+			info, err := os.Stat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantMode, info.Mode().Perm())
+			if tt.link {
+				info, err := os.Lstat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.NotZero(t, info.Mode()&os.ModeSymlink, "symlink was replaced")
+			}
+		})
+	}
+}
+
+// Test_writeFile_concurrent appends contexts to the same contexts file from
+// several Configs in parallel, like concurrent ktx processes. Each
+// read-modify-write holds the lock, so no context is lost.
+func Test_writeFile_concurrent(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"kube":            "contexts: []\n",
+		"contexts_x.yaml": "[]\n",
+		"config.jsonnet": "{ kube_configs: [{ alias: 'x', path: '" + filepath.Join(dir, "kube") + "', " +
+			"contexts: std.parseYaml(importstr 'contexts_x.yaml') }] }",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writers := 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		c, err := Get(filepath.Join(dir, "config.jsonnet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- func() error {
+				unlock, err := c.Lock()
+				if err != nil {
+					return err
+				}
+				defer unlock()
+				if err := c.Reload(); err != nil {
+					return err
+				}
+				k := c.KubeConfs[0]
+				k.Contexts = append(k.Contexts, &ContextEntry{Name: fmt.Sprintf("writer-%02d", i)})
+				return k.Save()
+			}()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	c, err := Get(filepath.Join(dir, "config.jsonnet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, c.KubeConfs[0].Contexts, writers, "contexts of concurrent writers are lost")
+	tmp, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	assert.NoError(t, err)
+	assert.Empty(t, tmp, "temporary files are left behind")
+}
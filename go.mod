@@ -12,6 +12,7 @@ require (
 	github.com/google/go-jsonnet v0.20.0
 	github.com/peterbueschel/jsonnet-custom-importers v0.0.6-alpha
 	github.com/stretchr/testify v1.8.1
+	golang.org/x/sys v0.26.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -40,7 +41,6 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
 	golang.org/x/sync v0.9.0 // indirect
-	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
@@ -0,0 +1,91 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// stateVersion is the version of the schema of the .state file. Older
+	// files are migrated, when they are read.
+	stateVersion = 2
+	// historySize is the maximum number of entries in the history.
+	historySize = 50
+)
+
+var (
+	// ErrHistory is returned if the history has no entry for a step.
+	ErrHistory = errors.New("no such entry in the history")
+	// ErrStateVersion is returned for a .state file of a newer version.
+	ErrStateVersion = errors.New("unsupported version of the state file")
+
+	// now returns the time of a switch.
+	now = time.Now
+)
+
+// HistoryEntry is a single switch in the history of the state.
+type HistoryEntry struct {
+	// KubeConfig is the path of the kube config of the context.
+	KubeConfig string `yaml:"kubeConfig"`
+	// Context is the name of the context inside the kube config.
+	Context   string    `yaml:"context"`
+	Namespace string    `yaml:"namespace,omitempty"`
+	Time      time.Time `yaml:"time,omitempty"`
+	// External is true for a switch made outside of ktx, like "kubectl
+	// config use-context", which was found later on.
+	External bool `yaml:"external,omitempty"`
+}
+
+// push adds the context as newest entry to the history. A switch to the
+// context of the newest entry only updates this entry. The history is
+// copied, so that a copy of the state stays unchanged.
+func (s *State) push(conf, context, namespace string) {
+	e := HistoryEntry{KubeConfig: conf, Context: context, Namespace: namespace, Time: now()}
+	history := []HistoryEntry{e}
+	for idx, h := range s.History {
+		if idx == 0 && h.KubeConfig == conf && h.Context == context {
+			continue
+		}
+		history = append(history, h)
+	}
+	if len(history) > historySize {
+		history = history[:historySize]
+	}
+	s.History = history
+}
+
+// Step returns the entry of the history n steps back. Step 0 is the current
+// context.
+func (s *State) Step(n int) (HistoryEntry, error) {
+	if n < 0 || n >= len(s.History) {
+		return HistoryEntry{}, fmt.Errorf("%w: %d steps back, the history has %d entries", ErrHistory, n, len(s.History))
+	}
+	return s.History[n], nil
+}
+
+// migrate updates a state read from an older .state file to the current
+// schema. Version 1 has no version field and stores only the current and
+// the last context, which become the first entries of the history.
+func (s *State) migrate() error {
+	if s.Version > stateVersion {
+		return fmt.Errorf("%w: %d, supported up to %d", ErrStateVersion, s.Version, stateVersion)
+	}
+	if s.Version == stateVersion {
+		return nil
+	}
+	if len(s.History) == 0 {
+		if s.CurrentContext != "" {
+			s.History = append(s.History, HistoryEntry{
+				KubeConfig: s.CurrentConf, Context: s.CurrentContext, Namespace: s.CurrentNamespace,
+			})
+		}
+		if s.LastContext != "" && (s.LastContext != s.CurrentContext || s.LastConf != s.CurrentConf) {
+			s.History = append(s.History, HistoryEntry{
+				KubeConfig: s.LastConf, Context: s.LastContext, Namespace: s.LastNamespace,
+			})
+		}
+	}
+	s.Version = stateVersion
+	return nil
+}
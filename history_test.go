@@ -0,0 +1,119 @@
+package k8sctx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_push(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Minute)
+	}
+	t.Cleanup(func() { now = time.Now })
+
+	s := &State{}
+	s.update("a", "a1", "")
+	s.update("b", "b1", "web")
+	backup := *s
+	s.update("b", "b1", "monitoring")
+	assert.Len(t, s.History, 2, "switch to the current context is not added")
+	assert.Equal(t, HistoryEntry{KubeConfig: "b", Context: "b1", Namespace: "monitoring", Time: start.Add(3 * time.Minute)}, s.History[0])
+	assert.Equal(t, "web", backup.History[0].Namespace, "copy of the state was changed")
+	assert.Equal(t, stateVersion, s.Version)
+
+	for i := 0; i < historySize+5; i++ {
+		s.update("c", fmt.Sprintf("c%d", i), "")
+	}
+	assert.Len(t, s.History, historySize)
+	assert.Equal(t, fmt.Sprintf("c%d", historySize+4), s.History[0].Context)
+}
+
+func TestState_Step(t *testing.T) {
+	s := &State{History: []HistoryEntry{{Context: "a"}, {Context: "b"}}}
+	tests := []struct {
+		name    string
+		n       int
+		want    string
+		wantErr error
+	}{
+		{name: "current", n: 0, want: "a"},
+		{name: "previous", n: 1, want: "b"},
+		{name: "negative - too far", n: 2, wantErr: ErrHistory},
+		{name: "negative - negative step", n: -1, wantErr: ErrHistory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Step(tt.n)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Context)
+		})
+	}
+}
+
+func TestState_load_migrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantHistory []HistoryEntry
+		wantErr     error
+	}{
+		{
+			name:    "version 1",
+			content: "currentKubeConfig: a\ncurrentContext: a1\nlastKubeConfig: b\nlastContext: b1\n",
+			wantHistory: []HistoryEntry{
+				{KubeConfig: "a", Context: "a1"},
+				{KubeConfig: "b", Context: "b1"},
+			},
+		},
+		{
+			name:        "version 1 - same current and last context",
+			content:     "currentKubeConfig: a\ncurrentContext: a1\nlastKubeConfig: a\nlastContext: a1\n",
+			wantHistory: []HistoryEntry{{KubeConfig: "a", Context: "a1"}},
+		},
+		{
+			name:        "version 1 - empty",
+			content:     "filename: .state\n",
+			wantHistory: nil,
+		},
+		{
+			name:        "current version",
+			content:     "version: 2\ncurrentKubeConfig: a\ncurrentContext: a1\nhistory:\n- kubeConfig: a\n  context: a1\n  time: 2024-01-01T00:00:00Z\n",
+			wantHistory: []HistoryEntry{{KubeConfig: "a", Context: "a1", Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:    "negative - newer version",
+			content: "version: 99\n",
+			wantErr: ErrStateVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".state")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			s := &State{Filename: path}
+			err := s.load()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, ErrParseStateFile)
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, stateVersion, s.Version)
+			assert.Equal(t, tt.wantHistory, s.History)
+		})
+	}
+}
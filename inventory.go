@@ -0,0 +1,124 @@
+package k8sctx
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the values of credentials in the inventory.
+const redacted = "REDACTED"
+
+// InventoryEntry describes a context together with its cluster and user for
+// scripts and other tools. It never holds credentials: the user is only
+// named and secrets in the server URL or the fields are redacted.
+type InventoryEntry struct {
+	// Name is the alias or the name of the context as shown in the list.
+	Name string `json:"name" yaml:"name"`
+	// Context is the name of the context inside the kube config.
+	Context string `json:"context" yaml:"context"`
+	// Config is the alias of the kube config and KubeConfig its path.
+	Config     string `json:"config" yaml:"config"`
+	KubeConfig string `json:"kubeconfig" yaml:"kubeconfig"`
+	Cluster    string `json:"cluster" yaml:"cluster"`
+	Server     string `json:"server,omitempty" yaml:"server,omitempty"`
+	User       string `json:"user" yaml:"user"`
+	Namespace  string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Current and Previous mark the current and the previous context.
+	Current     bool              `json:"current" yaml:"current"`
+	Previous    bool              `json:"previous" yaml:"previous"`
+	Protected   bool              `json:"protected" yaml:"protected"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// Inventory returns the inventory entries of the list items (see
+// CreateListItems and SelectListItems). The current and the previous context
+// are marked, like the ones of the State.
+func (c *Config) Inventory(items []ContextItem, current, previous HistoryEntry) []InventoryEntry {
+	entries := make([]InventoryEntry, 0, len(items))
+	for _, i := range items {
+		e := InventoryEntry{
+			Name:        i.Name,
+			Context:     i.Context,
+			Config:      i.Config,
+			Protected:   i.Protected,
+			Description: redactDescription(i.Description, i.Fields),
+			Fields:      redactFields(i.Fields),
+			Namespace:   i.Fields["namespace"],
+		}
+		if kcnf := c.GetKubeConfigByAlias(i.Config); kcnf != nil {
+			e.KubeConfig = kcnf.Path
+			e.Current = current.KubeConfig == kcnf.Path && current.Context == i.Context
+			e.Previous = previous.KubeConfig == kcnf.Path && previous.Context == i.Context
+			if kcnf.KubeConfig != nil {
+				if ctx, _, err := kcnf.KubeConfig.GetContextBy(i.Context); err == nil {
+					e.Cluster, e.User = ctx.Cluster, ctx.User
+					e.Server = kcnf.KubeConfig.Server(ctx.Cluster)
+					if ctx.Namespace != "" {
+						e.Namespace = ctx.Namespace
+					}
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Server returns the server URL of the cluster with a redacted password.
+func (k *KubeConfig) Server(cluster string) string {
+	for _, cl := range k.Clusters {
+		if cl.Name != cluster {
+			continue
+		}
+		fields, ok := cl.Cluster.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		server, _ := fields["server"].(string)
+		if u, err := url.Parse(server); err == nil && u.User != nil {
+			u.User = url.User(redacted)
+			return u.String()
+		}
+		return server
+	}
+	return ""
+}
+
+// redactFields returns a copy of the fields, where the values of fields
+// named like credentials, e.g. "token" or "db_password", are redacted.
+func redactFields(fields map[string]string) map[string]string {
+	if fields == nil {
+		return nil
+	}
+	out := make(map[string]string, len(fields))
+	for k, v := range fields {
+		out[k] = v
+		if isSecret(k) {
+			out[k] = redacted
+		}
+	}
+	return out
+}
+
+// redactDescription replaces the values of the secret fields inside the
+// description.
+func redactDescription(description string, fields map[string]string) string {
+	for k, v := range fields {
+		if v != "" && isSecret(k) {
+			description = strings.ReplaceAll(description, v, redacted)
+		}
+	}
+	return description
+}
+
+// isSecret returns true if the field is named like a credential.
+func isSecret(field string) bool {
+	field = strings.ToLower(field)
+	for _, key := range append(credentialKeys, "secret") {
+		if strings.Contains(field, key) {
+			return true
+		}
+	}
+	return false
+}
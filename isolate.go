@@ -0,0 +1,144 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isolatedDir is the folder inside the config dir for the temporary kube
+// configs of "ktx shell".
+const isolatedDir = "isolated"
+
+// ErrIsolate is returned if no self-contained kube config can be created for
+// a context.
+var ErrIsolate = errors.New("failed to isolate context")
+
+// pathKeys are the fields of clusters and users holding file paths, which are
+// relative to the kube config file.
+var pathKeys = []string{"certificate-authority", "client-certificate", "client-key", "tokenFile"}
+
+type (
+	// isolatedKubeConfig is a self-contained kube config with a single
+	// context together with its cluster and user.
+	isolatedKubeConfig struct {
+		APIVersion     string         `yaml:"apiVersion"`
+		Kind           string         `yaml:"kind"`
+		Preferences    interface{}    `yaml:"preferences,omitempty"`
+		Clusters       []clusterEntry `yaml:"clusters"`
+		Users          []userEntry    `yaml:"users"`
+		Contexts       []KubeContext  `yaml:"contexts"`
+		CurrentContext string         `yaml:"current-context"`
+	}
+	// clusterEntry is a cluster of a kube config.
+	clusterEntry struct {
+		Name    string      `yaml:"name"`
+		Cluster interface{} `yaml:"cluster"`
+	}
+	// userEntry is a user of a kube config.
+	userEntry struct {
+		Name string      `yaml:"name"`
+		User interface{} `yaml:"user"`
+	}
+)
+
+// Isolate returns a self-contained kube config, which holds only the context
+// with the given name together with its cluster, user and namespace. Relative
+// file paths of the cluster and user are made absolute, so that the kube
+// config can be stored anywhere.
+func (k *KubeConfig) Isolate(contextName string) ([]byte, error) {
+	ctx, _, err := k.GetContextBy(contextName)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(k.Path)
+	cnf := isolatedKubeConfig{
+		APIVersion:     k.APIVersion,
+		Kind:           k.Kind,
+		Preferences:    k.Preferences,
+		Clusters:       []clusterEntry{},
+		Users:          []userEntry{},
+		Contexts:       []KubeContext{*ctx},
+		CurrentContext: ctx.Name,
+	}
+	if cnf.APIVersion == "" {
+		cnf.APIVersion = "v1"
+	}
+	if cnf.Kind == "" {
+		cnf.Kind = "Config"
+	}
+	for _, c := range k.Clusters {
+		if c.Name == ctx.Cluster {
+			cnf.Clusters = append(cnf.Clusters, clusterEntry{Name: c.Name, Cluster: absPaths(c.Cluster, dir)})
+		}
+	}
+	for _, u := range k.Users {
+		if u.Name == ctx.User {
+			cnf.Users = append(cnf.Users, userEntry{Name: u.Name, User: absPaths(u.User, dir)})
+		}
+	}
+	return yaml.Marshal(&cnf)
+}
+
+// absPaths returns a copy of the cluster or user entry with the relative
+// file paths joined to dir.
+func absPaths(entry interface{}, dir string) interface{} {
+	m, ok := entry.(map[string]interface{})
+	if !ok {
+		return entry
+	}
+	c := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		c[key] = value
+	}
+	for _, key := range pathKeys {
+		if p, ok := c[key].(string); ok && p != "" && !filepath.IsAbs(p) {
+			c[key] = filepath.Join(dir, p)
+		}
+	}
+	return c
+}
+
+// IsIsolatedFile returns true if the path belongs to the temporary kube
+// configs written by Isolate.
+func (c *Config) IsIsolatedFile(path string) bool {
+	return filepath.Dir(filepath.Clean(path)) == filepath.Join(c.Dir, isolatedDir)
+}
+
+// Isolate writes a self-contained kube config for the context of the kube
+// config k into the "isolated" folder of the config dir. It returns the path
+// of the file and a function, which removes it again.
+func (c *Config) Isolate(k *KubeConf, contextName string) (string, func() error, error) {
+	cnf, err := k.KubeConfig.Isolate(contextName)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w '%s': %w", ErrIsolate, contextName, err)
+	}
+	dir := filepath.Join(c.Dir, isolatedDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, fmt.Errorf("%w '%s': %w", ErrIsolate, contextName, err)
+	}
+	f, err := os.CreateTemp(dir, "*.kubeconfig")
+	if err != nil {
+		return "", nil, fmt.Errorf("%w '%s': %w", ErrIsolate, contextName, err)
+	}
+	remove := func() error {
+		if err := os.Remove(f.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	_, err = f.Write(cnf)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), FileMode)
+	}
+	if err != nil {
+		return "", nil, errors.Join(fmt.Errorf("%w '%s': %w", ErrIsolate, contextName, err), remove())
+	}
+	return f.Name(), remove, nil
+}
@@ -0,0 +1,98 @@
+package k8sctx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestKubeConfig_Isolate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	content := `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    certificate-authority: certs/ca.crt
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev, namespace: monitoring}
+- name: prod
+  context: {cluster: prod, user: prod}
+current-context: prod
+users:
+- name: dev
+  user:
+    client-key: /etc/dev.key
+    token: secret
+- name: prod
+  user:
+    token: other
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = k.Isolate("unknown")
+	assert.ErrorIs(t, err, ErrNoContext)
+
+	cnf, err := k.Isolate("dev")
+	if err != nil {
+		t.Fatalf("KubeConfig.Isolate() error = %v", err)
+	}
+	got := &KubeConfig{}
+	if err := yaml.Unmarshal(cnf, got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "dev", got.CurrentContext)
+	assert.Equal(t, []KubeContext{{Name: "dev", Context: &Context{Cluster: "dev", User: "dev", Namespace: "monitoring"}}}, got.Contexts)
+	if assert.Len(t, got.Clusters, 1) {
+		assert.Equal(t, map[string]interface{}{
+			"certificate-authority": filepath.Join(dir, "certs", "ca.crt"),
+			"server":                "https://dev.example.com",
+		}, got.Clusters[0].Cluster)
+	}
+	if assert.Len(t, got.Users, 1) {
+		assert.Equal(t, map[string]interface{}{"client-key": "/etc/dev.key", "token": "secret"}, got.Users[0].User)
+	}
+	assert.Equal(t, "certs/ca.crt", k.Clusters[0].Cluster.(map[string]interface{})["certificate-authority"],
+		"original kube config was changed")
+}
+
+func TestConfig_Isolate(t *testing.T) {
+	c, _ := switchSetup(t)
+	path, remove, err := c.Isolate(c.KubeConfs[1], "b2")
+	if err != nil {
+		t.Fatalf("Config.Isolate() error = %v", err)
+	}
+	assert.Equal(t, filepath.Join(c.Dir, isolatedDir), filepath.Dir(path))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, FileMode, info.Mode().Perm())
+	k, err := GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "b2", k.CurrentContext)
+	assert.Equal(t, []string{"b2"}, k.GetContextNames())
+
+	assert.NoError(t, remove())
+	assert.NoFileExists(t, path)
+
+	_, _, err = c.Isolate(c.KubeConfs[1], "a1")
+	assert.ErrorIs(t, err, ErrIsolate)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -34,7 +35,8 @@ type (
 		// Preferences comes from the underlying K8s Kube Config specs.
 		Preferences interface{} `yaml:"preferences"`
 		// Contexts comes from the underlying K8s Kube Config specs and will be
-		// next to the CurrentContext interesting part for us.
+		// next to the CurrentContext interesting part for us. The contexts
+		// keep the order of the file; use SortedContexts for display.
 		Contexts []KubeContext `yaml:"contexts"`
 		// CurrentContext contains the desired context name.
 		CurrentContext string `yaml:"current-context"`
@@ -48,15 +50,25 @@ type (
 			Name string      `yaml:"name"`
 			User interface{} `yaml:"user"`
 		} `yaml:"users"`
+		// raw holds the file content as read from disk.
+		raw []byte
+		// doc holds the parsed file content. Together with raw it is used to
+		// write back only the current context and the namespaces, while all
+		// other parts of the file stay untouched.
+		doc *yaml.Node
 	}
 	KubeConfigs []KubeConfig
 )
 
 var (
-	ErrReadKubeConfig  = errors.New("failed to read from kube config file")
-	ErrParseKubeConfig = errors.New("failed to parse kube config file")
-	ErrDuplContext     = errors.New("duplicated context name")
-	ErrNoContext       = errors.New("no context found")
+	ErrReadKubeConfig      = errors.New("failed to read from kube config file")
+	ErrParseKubeConfig     = errors.New("failed to parse kube config file")
+	ErrDuplContext         = errors.New("duplicated context name")
+	ErrNoContext           = errors.New("no context found")
+	ErrInsecurePermissions = errors.New("kube config with credentials is readable by group or others")
+
+	// credentialKeys are the fields of a user entry holding secrets.
+	credentialKeys = []string{"token", "password", "client-key", "client-key-data", "auth-provider"}
 )
 
 func home(path string) string {
@@ -79,10 +91,16 @@ func (k *KubeConfig) Read() error {
 		return fmt.Errorf("%w: '%s', err: %w", ErrReadKubeConfig, k.Path, err)
 	}
 
-	err = yaml.Unmarshal(f, &k)
-	if err != nil {
+	doc := &yaml.Node{}
+	if err = yaml.Unmarshal(f, doc); err != nil {
 		return fmt.Errorf("%w: '%s', err: %w", ErrParseKubeConfig, k.Path, err)
 	}
+	if doc.Kind != 0 {
+		if err = doc.Decode(k); err != nil {
+			return fmt.Errorf("%w: '%s', err: %w", ErrParseKubeConfig, k.Path, err)
+		}
+	}
+	k.raw, k.doc = f, doc
 
 	dupl := make(map[string]int)
 	for _, ctx := range k.Contexts {
@@ -93,16 +111,56 @@ func (k *KubeConfig) Read() error {
 			return fmt.Errorf("%w %s in %s", ErrDuplContext, d, k.Path)
 		}
 	}
+	return nil
+}
 
-	sort.Slice(k.Contexts, func(i, j int) bool {
-		return k.Contexts[i].Name < k.Contexts[j].Name
+// SortedContexts returns a copy of the contexts sorted by name. The order of
+// the Contexts itself is kept as it is in the file.
+func (k *KubeConfig) SortedContexts() []KubeContext {
+	sorted := append([]KubeContext{}, k.Contexts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
 	})
+	return sorted
+}
+
+// HasCredentials returns true if one of the users holds a token, a password or
+// a client key.
+func (k *KubeConfig) HasCredentials() bool {
+	for _, u := range k.Users {
+		user, ok := u.User.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range credentialKeys {
+			if v, exists := user[key]; exists && v != nil && v != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckPermissions returns ErrInsecurePermissions if the kube config holds
+// credentials and the file is readable by the group or others.
+func (k *KubeConfig) CheckPermissions() error {
+	if runtime.GOOS == "windows" || !k.HasCredentials() {
+		return nil
+	}
+	info, err := os.Stat(k.Path)
+	if err != nil {
+		return fmt.Errorf("%w: '%s', err: %w", ErrReadKubeConfig, k.Path, err)
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		return fmt.Errorf("%w: '%s' has mode %#o, run 'chmod 600 %s' to fix it",
+			ErrInsecurePermissions, k.Path, mode, k.Path)
+	}
 	return nil
 }
 
-// GetProfilesNames returns all context names
+// GetContextNames returns all context names sorted by name.
 func (k *KubeConfig) GetContextNames() (names []string) {
-	for _, p := range k.Contexts {
+	for _, p := range k.SortedContexts() {
 		names = append(names, p.Name)
 	}
 	return
@@ -152,25 +210,77 @@ func (k *KubeConfig) AddNamespaceTo(contextName, namespace string) error {
 	return k.SaveContexts()
 }
 
+// SaveContexts writes the current context and the namespaces of the contexts
+// into the kube config file. All other parts of the file, like comments,
+// extensions or the JSON format, are kept as they are.
 func (k *KubeConfig) SaveContexts() error {
-	cnf, err := yaml.Marshal(&k)
+	cnf, err := k.render()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(k.Path, cnf, 0644)
+	if err := writeFile(k.Path, cnf, FileMode); err != nil {
+		return err
+	}
+	return k.written(cnf)
 }
 
-func (k *KubeConfig) SyncContexts(c *KubeConf) error {
-	names := k.GetContextNames()
-	for _, n := range names {
-		if c.Exists(n) {
+// written updates the document of the kube config with the content written
+// to disk, so that following changes are applied to the new content.
+func (k *KubeConfig) written(cnf []byte) error {
+	if k.doc == nil {
+		return nil
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(cnf, doc); err != nil {
+		return fmt.Errorf("%w: '%s', err: %w", ErrParseKubeConfig, k.Path, err)
+	}
+	k.raw, k.doc = cnf, doc
+	return nil
+}
+
+// render returns the file content with the current context and the
+// namespaces applied to the content read from disk. A kube config, which was
+// not read from a file, is marshaled as a whole.
+func (k *KubeConfig) render() ([]byte, error) {
+	if k.doc == nil {
+		return yaml.Marshal(&k)
+	}
+	top := root(k.doc)
+	if top == nil {
+		return nil, fmt.Errorf("%w: '%s', err: no mapping found", ErrParseKubeConfig, k.Path)
+	}
+	e := newEditor(k.raw, k.doc)
+	e.set(top, "current-context", k.CurrentContext)
+	_, contexts := lookup(top, "contexts")
+	for _, ctx := range k.Contexts {
+		if ctx.Context == nil || ctx.Namespace == "" {
+			continue
+		}
+		if n := findContext(contexts, ctx.Name); n != nil {
+			e.set(n, "namespace", ctx.Namespace)
+		}
+	}
+	if cnf, err := e.apply(); err == nil && k.matches(cnf) {
+		return cnf, nil
+	}
+	return e.encode(k.doc)
+}
+
+// matches returns true if the given file content contains the current context
+// and namespaces of k.
+func (k *KubeConfig) matches(cnf []byte) bool {
+	got := &KubeConfig{}
+	if err := yaml.Unmarshal(cnf, got); err != nil || got.CurrentContext != k.CurrentContext {
+		return false
+	}
+	for _, ctx := range k.Contexts {
+		if ctx.Context == nil || ctx.Namespace == "" {
 			continue
 		}
-		ctx, _, err := k.GetContextBy(n)
-		if err != nil {
-			return err
+		c, _, err := got.GetContextBy(ctx.Name)
+		if err != nil || c.Namespace != ctx.Namespace {
+			return false
 		}
-		c.Contexts = append(c.Contexts, map[string]string{"name": ctx.Name, "kubeconfig": k.Path})
 	}
-	return c.Save()
+	return true
 }
@@ -2,6 +2,8 @@ package k8sctx
 
 import (
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,14 +30,12 @@ func TestKubeConfig_SyncContexts(t *testing.T) {
 			args: args{
 				k: &KubeConf{
 					ContextFile: "testdata/contexts_sync.yaml",
-					Contexts: []map[string]string{
+					Contexts: []*ContextEntry{
 						{
-							"alias":       "avap:1",
-							"cluster":     "cluster1",
-							"environment": "prod",
-							"name":        "aws:prod:accountId:us-east-1:cluster1",
-							"namespace":   "monitoring",
-							"region":      "us-east-1",
+							Alias:     "avap:1",
+							Name:      "aws:prod:accountId:us-east-1:cluster1",
+							Namespace: "monitoring",
+							Metadata:  map[string]any{"cluster": "cluster1", "environment": "prod", "region": "us-east-1"},
 						},
 					},
 				},
@@ -46,7 +46,7 @@ func TestKubeConfig_SyncContexts(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			k := testKubeConfig
-			if err := k.SyncContexts(tt.args.k); (err != nil) != tt.wantErr {
+			if _, err := k.SyncContexts(tt.args.k, OrphansMark); (err != nil) != tt.wantErr {
 				t.Errorf("KubeConfig.SyncContexts() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -154,3 +154,90 @@ func TestKubeConfig_SetContextTo(t *testing.T) {
 	}
 	t.Cleanup(func() { os.RemoveAll("testdata/kube.config.set") })
 }
+
+func TestKubeConfig_CheckPermissions(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	const (
+		withToken    = "users:\n- name: a\n  user:\n    token: secret\n"
+		withKey      = "users:\n- name: a\n  user:\n    client-key-data: c2VjcmV0\n"
+		withExecOnly = "users:\n- name: a\n  user:\n    exec:\n      command: aws\n"
+	)
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		wantErr bool
+	}{
+		{name: "token - private", content: withToken, mode: 0600},
+		{name: "token - group readable", content: withToken, mode: 0640, wantErr: true},
+		{name: "client key - world readable", content: withKey, mode: 0644, wantErr: true},
+		{name: "no credentials - world readable", content: withExecOnly, mode: 0644},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.content), tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			k, err := GetKubeConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = k.CheckPermissions()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KubeConfig.CheckPermissions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInsecurePermissions)
+			}
+		})
+	}
+}
+
+func TestKubeConfig_keepOrder(t *testing.T) {
+	t.Parallel()
+	content := "contexts:\n" +
+		"- name: zeta\n  context:\n    cluster: z\n    user: z\n" +
+		"- name: alpha\n  context:\n    cluster: a\n    user: a\n" +
+		"- name: mu\n  context:\n    cluster: m\n    user: m\n" +
+		"current-context: zeta\n"
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"alpha", "mu", "zeta"}, k.GetContextNames())
+
+	c := &Config{
+		State:     &State{Filename: filepath.Join(dir, ".state")},
+		KubeConfs: []*KubeConf{{Path: path, KubeConfig: k}},
+	}
+	if err := k.AddNamespaceTo("mu", "monitoring"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SwitchTo(c.KubeConfs[0], "alpha"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, ctx := range got.Contexts {
+		names = append(names, ctx.Name)
+	}
+	assert.Equal(t, []string{"zeta", "alpha", "mu"}, names)
+	assert.Equal(t, "alpha", got.CurrentContext)
+}
@@ -0,0 +1,88 @@
+package k8sctx
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// lockName is the file inside the config dir, whose lock serializes the
+// changes of concurrent ktx processes.
+const lockName = ".lock"
+
+// dirLock is the lock of the config dir. It is shared by a Config with its
+// kube configs and sessions and held for a whole read-modify-write of the
+// kube configs, the contexts files and the state files. The lock is
+// reentrant for its holder, so that locked operations can call each other,
+// like a switch, which updates the contexts file. The holder is the Config
+// and not a goroutine: goroutines, which share a Config, don't wait for each
+// other.
+type dirLock struct {
+	path   string
+	mu     sync.Mutex
+	held   int
+	unlock func()
+}
+
+func newDirLock(dir string) *dirLock {
+	return &dirLock{path: filepath.Join(dir, lockName)}
+}
+
+// lock takes the lock and returns the function to release it. A nil lock,
+// like the one of a KubeConf without Config, does nothing.
+func (l *dirLock) lock() (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held == 0 {
+		unlock, err := lockFile(l.path)
+		if err != nil {
+			return nil, fmt.Errorf("lock '%s': %w", l.path, err)
+		}
+		l.unlock = unlock
+	}
+	l.held++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.held--; l.held == 0 {
+				l.unlock()
+				l.unlock = nil
+			}
+		})
+	}, nil
+}
+
+// Lock takes the lock of the config dir, which serializes the changes of
+// concurrent ktx processes and of Configs in the same process. All methods,
+// which write files, take it on their own. Hold it in addition to read and
+// change the config in one step, e.g. Reload, Sync and SaveFingerprint. The
+// lock is reentrant, so it doesn't serialize goroutines sharing a Config.
+func (c *Config) Lock() (func(), error) {
+	return c.lock.lock()
+}
+
+// Reload reads the config files again (see Get). The lock of the config,
+// if it is held, stays held.
+func (c *Config) Reload() error {
+	fresh, err := Get(c.GlobalConfig)
+	if err != nil {
+		return err
+	}
+	fresh.Terminal = c.Terminal
+	fresh.setLock(c.lock)
+	*c = *fresh
+	return nil
+}
+
+// setLock shares the lock with the kube configs.
+func (c *Config) setLock(l *dirLock) {
+	c.lock = l
+	for _, cnf := range c.KubeConfs {
+		cnf.lock = l
+	}
+}
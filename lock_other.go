@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package k8sctx
+
+// lockFile is a no-op on platforms without file locking.
+func lockFile(string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,80 @@
+package k8sctx
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_lock_readModifyWrite races the state updates of two configs, like two
+// ktx processes. Without the lock, one config overwrites the state written
+// by the other one in between its read and its write.
+func Test_lock_readModifyWrite(t *testing.T) {
+	dir := t.TempDir()
+	updates := 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*updates)
+	for _, name := range []string{"a", "b"} {
+		c := &Config{GlobalConfig: filepath.Join(dir, "config.jsonnet")}
+		c.setup()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				k := &KubeConf{Path: "/kube/" + name}
+				errs <- c.UpdateState(k, fmt.Sprintf("%s-%d", name, i))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	s := &State{Filename: filepath.Join(dir, ".state")}
+	assert.NoError(t, s.load())
+	assert.Len(t, s.History, 2*updates, "updates of the state are lost")
+}
+
+func Test_dirLock_reentrant(t *testing.T) {
+	l := newDirLock(t.TempDir())
+	outer, err := l.lock()
+	if !assert.NoError(t, err) {
+		return
+	}
+	inner, err := l.lock()
+	if !assert.NoError(t, err) {
+		return
+	}
+	inner()
+	inner()
+	assert.Equal(t, 1, l.held)
+	outer()
+	assert.Equal(t, 0, l.held)
+
+	// a second config in the same process waits for the first one
+	other := newDirLock(filepath.Dir(l.path))
+	unlock, err := l.lock()
+	if !assert.NoError(t, err) {
+		return
+	}
+	locked := make(chan struct{})
+	go func() {
+		u, err := other.lock()
+		assert.NoError(t, err)
+		close(locked)
+		u()
+	}()
+	select {
+	case <-locked:
+		t.Fatal("lock is held twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+}
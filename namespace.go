@@ -0,0 +1,232 @@
+package k8sctx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// namespacesKey is the field of a context, which holds a list of namespaces,
+// either as list or comma separated. It is used, if the cluster cannot be
+// reached.
+const namespacesKey = "namespaces"
+
+var (
+	// ErrNamespaces is returned if the namespaces of a context can neither be
+	// read from the cluster nor from the contexts file.
+	ErrNamespaces = errors.New("failed to list namespaces")
+	// ErrUnsupportedAuth is returned for users, which need an exec plugin or
+	// an auth provider to get their credentials.
+	ErrUnsupportedAuth = errors.New("unsupported authentication")
+)
+
+// namespaceList is the part of the response of the Kubernetes API, which is
+// needed to get the names of the namespaces.
+type namespaceList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// Namespaces returns the namespaces of the context with the given name. The
+// namespaces are read from the cluster with the credentials of the context.
+// If the cluster cannot be reached, the "namespaces" field of the context in
+// the contexts file is used instead and fromCluster is false.
+func (k *KubeConf) Namespaces(ctx context.Context, contextName string) (names []string, fromCluster bool, err error) {
+	names, err = k.KubeConfig.ListNamespaces(ctx, contextName)
+	if err == nil {
+		return names, true, nil
+	}
+	if c, idx := k.GetContextBy(contextName); idx != -1 {
+		if declared := c.Values(namespacesKey); len(declared) > 0 {
+			return declared, false, nil
+		}
+	}
+	return nil, false, fmt.Errorf("%w of '%s': %w", ErrNamespaces, contextName, err)
+}
+
+// SetNamespace writes the namespace of the context into the kube config. If
+// the context has a namespace in the contexts file, it is updated as well,
+// so that the next sync doesn't revert the change.
+func (k *KubeConf) SetNamespace(contextName, namespace string) error {
+	unlock, err := k.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// read again, in case the file was changed since it was loaded
+	if k.KubeConfig.doc != nil {
+		if err := k.KubeConfig.Read(); err != nil {
+			return err
+		}
+	}
+	if err := k.KubeConfig.AddNamespaceTo(contextName, namespace); err != nil {
+		return err
+	}
+	return k.keepNamespace(contextName, namespace)
+}
+
+// keepNamespace updates the namespace of the context in the contexts file,
+// if the context has one there.
+func (k *KubeConf) keepNamespace(contextName, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	c, idx := k.GetContextBy(contextName)
+	if idx == -1 || c.Namespace == "" || c.Namespace == namespace {
+		return nil
+	}
+	c.Namespace = namespace
+	return k.Save()
+}
+
+// ListNamespaces reads the names of the namespaces from the cluster of the
+// context with the given name. Only static credentials are supported: tokens,
+// client certificates and basic auth.
+func (k *KubeConfig) ListNamespaces(ctx context.Context, contextName string) ([]string, error) {
+	kctx, _, err := k.GetContextBy(contextName)
+	if err != nil {
+		return nil, err
+	}
+	req, client, err := k.request(ctx, kctx.Context, "/api/v1/namespaces")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	list := namespaceList{}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, i := range list.Items {
+		names = append(names, i.Metadata.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// request returns a GET request for the path on the server of the cluster
+// together with a client, which uses the TLS settings of the cluster and the
+// credentials of the user.
+func (k *KubeConfig) request(ctx context.Context, kctx *Context, path string) (*http.Request, *http.Client, error) {
+	dir := filepath.Dir(k.Path)
+	var cluster, user map[string]interface{}
+	for _, c := range k.Clusters {
+		if c.Name == kctx.Cluster {
+			cluster, _ = absPaths(c.Cluster, dir).(map[string]interface{})
+		}
+	}
+	for _, u := range k.Users {
+		if u.Name == kctx.User {
+			user, _ = absPaths(u.User, dir).(map[string]interface{})
+		}
+	}
+	server := field(cluster, "server")
+	if server == "" {
+		return nil, nil, fmt.Errorf("no server found for cluster '%s'", kctx.Cluster)
+	}
+	for _, key := range []string{"exec", "auth-provider"} {
+		if _, exists := user[key]; exists {
+			return nil, nil, fmt.Errorf("%w: '%s' of user '%s'", ErrUnsupportedAuth, key, kctx.User)
+		}
+	}
+
+	cnf := &tls.Config{
+		ServerName:         field(cluster, "tls-server-name"),
+		InsecureSkipVerify: cluster["insecure-skip-tls-verify"] == true,
+	}
+	ca, err := data(cluster, "certificate-authority")
+	if err != nil {
+		return nil, nil, err
+	}
+	if ca != nil {
+		cnf.RootCAs = x509.NewCertPool()
+		if !cnf.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, nil, fmt.Errorf("invalid certificate authority of cluster '%s'", kctx.Cluster)
+		}
+	}
+	cert, err := data(user, "client-certificate")
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := data(user, "client-key")
+	if err != nil {
+		return nil, nil, err
+	}
+	if cert != nil && key != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		cnf.Certificates = []tls.Certificate{pair}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(server, "/")+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	token := field(user, "token")
+	if file := field(user, "tokenFile"); token == "" && file != "" {
+		t, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		token = strings.TrimSpace(string(t))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if name := field(user, "username"); name != "" {
+		req.SetBasicAuth(name, field(user, "password"))
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cnf
+	return req, &http.Client{Transport: transport}, nil
+}
+
+// field returns the string value of the key.
+func field(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// data returns the content of the file given by the key or the decoded
+// content of the "<key>-data" field.
+func data(m map[string]interface{}, key string) ([]byte, error) {
+	if d := field(m, key+"-data"); d != "" {
+		return base64.StdEncoding.DecodeString(d)
+	}
+	if path := field(m, key); path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
+
+// splitList returns the trimmed, non empty items of a comma separated list.
+func splitList(s string) []string {
+	items := []string{}
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			items = append(items, i)
+		}
+	}
+	return items
+}
@@ -0,0 +1,157 @@
+package k8sctx
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPIServer returns a TLS server, which lists the namespaces for the
+// token "secret", together with a kube config for it.
+func fakeAPIServer(t *testing.T) (*httptest.Server, *KubeConf) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/v1/namespaces" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"kind":"NamespaceList","items":[{"metadata":{"name":"kube-system"}},{"metadata":{"name":"default"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	cnf := fmt.Sprintf(`apiVersion: v1
+clusters:
+- name: fake
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+contexts:
+- name: fake
+  context: {cluster: fake, user: fake, namespace: default}
+- name: guest
+  context: {cluster: fake, user: guest}
+- name: plugin
+  context: {cluster: fake, user: plugin}
+users:
+- name: fake
+  user:
+    tokenFile: token
+- name: guest
+  user: {}
+- name: plugin
+  user:
+    exec: {command: login}
+current-context: fake
+`, srv.URL, base64.StdEncoding.EncodeToString(ca))
+	if err := os.WriteFile(path, []byte(cnf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := GetKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, &KubeConf{
+		Path:        path,
+		Alias:       "fake",
+		ContextFile: filepath.Join(dir, "contexts_fake.yaml"),
+		KubeConfig:  k,
+		Contexts: []*ContextEntry{
+			{Name: "fake", Namespace: "default"},
+			{Name: "guest", Metadata: map[string]any{"namespaces": "web, monitoring"}},
+			{Name: "plugin"},
+		},
+	}
+}
+
+func TestKubeConf_Namespaces(t *testing.T) {
+	_, k := fakeAPIServer(t)
+	tests := []struct {
+		name            string
+		context         string
+		want            []string
+		wantFromCluster bool
+		wantErr         error
+	}{
+		{
+			name:            "from cluster",
+			context:         "fake",
+			want:            []string{"default", "kube-system"},
+			wantFromCluster: true,
+		},
+		{
+			name:    "unauthorized - declared in contexts file",
+			context: "guest",
+			want:    []string{"web", "monitoring"},
+		},
+		{
+			name:    "negative - unsupported auth",
+			context: "plugin",
+			wantErr: ErrUnsupportedAuth,
+		},
+		{
+			name:    "negative - unknown context",
+			context: "missing",
+			wantErr: ErrNoContext,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fromCluster, err := k.Namespaces(context.Background(), tt.context)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, ErrNamespaces)
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantFromCluster, fromCluster)
+		})
+	}
+}
+
+func TestKubeConfig_ListNamespaces_unreachable(t *testing.T) {
+	srv, k := fakeAPIServer(t)
+	srv.Close()
+	_, err := k.KubeConfig.ListNamespaces(context.Background(), "fake")
+	assert.Error(t, err)
+}
+
+func TestKubeConf_SetNamespace(t *testing.T) {
+	_, k := fakeAPIServer(t)
+	assert.NoError(t, k.SetNamespace("fake", "kube-system"))
+	assert.NoError(t, k.SetNamespace("guest", "web"))
+
+	got, err := GetKubeConfig(k.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, ns := range map[string]string{"fake": "kube-system", "guest": "web"} {
+		ctx, _, _ := got.GetContextBy(name)
+		assert.Equal(t, ns, ctx.Namespace, "kube config namespace of %s", name)
+	}
+	file, err := k.fileNamespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{"fake": "kube-system"}, file)
+
+	assert.ErrorIs(t, k.SetNamespace("missing", "web"), ErrNoContext)
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package k8sctx
+
+// processAlive returns always true on platforms, where processes cannot be
+// looked up, so that no session is removed by mistake.
+func processAlive(int) bool {
+	return true
+}
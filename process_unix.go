@@ -0,0 +1,15 @@
+//go:build unix
+
+package k8sctx
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// processAlive returns true if a process with the id exists.
+func processAlive(pid int) bool {
+	err := unix.Kill(pid, 0)
+	return err == nil || errors.Is(err, unix.EPERM)
+}
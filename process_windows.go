@@ -0,0 +1,19 @@
+//go:build windows
+
+package k8sctx
+
+import "golang.org/x/sys/windows"
+
+// stillActive is the exit code of a running process.
+const stillActive = 259
+
+// processAlive returns true if a process with the id exists.
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	return windows.GetExitCodeProcess(h, &code) == nil && code == stillActive
+}
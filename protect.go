@@ -0,0 +1,33 @@
+package k8sctx
+
+import (
+	"errors"
+)
+
+// protectedKey marks a context in the contexts file or the config.jsonnet,
+// which needs a confirmation before it is used.
+const protectedKey = "protected"
+
+// ErrProtected is returned if a protected context is used without a
+// confirmation.
+var ErrProtected = errors.New("protected context needs a confirmation")
+
+// IsProtected returns true for a context marked with "protected: true".
+func IsProtected(ctx *ContextEntry) bool {
+	return ctx.Bool(protectedKey)
+}
+
+// Protected returns true, if the context with the given name is protected.
+func (k *KubeConf) Protected(contextName string) bool {
+	ctx, idx := k.GetContextBy(contextName)
+	return idx != -1 && IsProtected(ctx)
+}
+
+// DisplayName returns the alias of the context with the given name or the
+// name itself, if the context has no alias.
+func (k *KubeConf) DisplayName(contextName string) string {
+	if ctx, idx := k.GetContextBy(contextName); idx != -1 {
+		return displayName(ctx)
+	}
+	return contextName
+}
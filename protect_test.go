@@ -0,0 +1,37 @@
+package k8sctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeConf_Protected(t *testing.T) {
+	k := &KubeConf{
+		Contexts: []*ContextEntry{
+			{Name: "a", Alias: "prod", Metadata: map[string]any{protectedKey: "true"}},
+			{Name: "b", Metadata: map[string]any{protectedKey: "false"}},
+			{Name: "c", Metadata: map[string]any{protectedKey: "yes please"}},
+			{Name: "d"},
+		},
+	}
+	tests := []struct {
+		name        string
+		contextName string
+		want        bool
+	}{
+		{name: "positive - by name", contextName: "a", want: true},
+		{name: "positive - by alias", contextName: "prod", want: true},
+		{name: "negative - false", contextName: "b"},
+		{name: "negative - no boolean", contextName: "c"},
+		{name: "negative - not set", contextName: "d"},
+		{name: "negative - unknown context", contextName: "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, k.Protected(tt.contextName))
+		})
+	}
+	assert.Equal(t, "prod", k.DisplayName("a"))
+	assert.Equal(t, "b", k.DisplayName("b"))
+}
@@ -0,0 +1,188 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// OrphansMark keeps contexts, which were removed from the kube config,
+	// in the contexts file with the marker "stale: true".
+	OrphansMark = "mark"
+	// OrphansDelete removes contexts, which were removed from the kube
+	// config, from the contexts file.
+	OrphansDelete = "delete"
+
+	// staleKey marks an orphaned context in the contexts file.
+	staleKey = "stale"
+)
+
+// Origin holds the cluster and the user of a context in its kube config. The
+// origins are kept in the .state file to detect renamed contexts.
+type Origin struct {
+	Cluster string `yaml:"cluster"`
+	User    string `yaml:"user"`
+}
+
+// ErrOrphans is returned for an unknown "orphaned_contexts" setting.
+var ErrOrphans = errors.New("unknown value for orphaned_contexts")
+
+// Kinds of a ContextChange.
+const (
+	ContextAdded    = "added"
+	ContextRenamed  = "renamed"
+	ContextRemoved  = "removed"
+	ContextStale    = "stale"
+	ContextRestored = "restored"
+)
+
+// ContextChange describes a change of a contexts file made by SyncContexts.
+type ContextChange struct {
+	// Kind is one of ContextAdded, ContextRenamed, ContextRemoved,
+	// ContextStale or ContextRestored.
+	Kind string `json:"kind"`
+	// Name of the context.
+	Name string `json:"name"`
+	// From holds the previous name of a renamed context.
+	From string `json:"from,omitempty"`
+}
+
+func (c ContextChange) String() string {
+	switch c.Kind {
+	case ContextRenamed:
+		return fmt.Sprintf("renamed context '%s' to '%s'", c.From, c.Name)
+	case ContextStale:
+		return fmt.Sprintf("marked context '%s' as stale", c.Name)
+	default:
+		return fmt.Sprintf("%s context '%s'", c.Kind, c.Name)
+	}
+}
+
+// validOrphans returns ErrOrphans for an unknown orphans setting. An empty
+// setting is the same as OrphansMark.
+func validOrphans(orphans string) error {
+	switch orphans {
+	case "", OrphansMark, OrphansDelete:
+		return nil
+	}
+	return fmt.Errorf("%w: '%s', use '%s' or '%s'", ErrOrphans, orphans, OrphansMark, OrphansDelete)
+}
+
+// SyncContexts reconciles the contexts of c with the contexts of the kube
+// config and writes them into the contexts file:
+//
+//   - new contexts of the kube config are added.
+//   - contexts, which were removed from the kube config, are orphans. They
+//     are removed or marked with "stale: true", depending on orphans.
+//   - an orphan and a new context with the same cluster and user are
+//     treated as a rename. The new context keeps the alias and the other
+//     fields of the orphan.
+//
+// The origins of the contexts are updated in c (see Config.Sync). The
+// contexts file is only written, if something changed. The changes are
+// returned.
+func (k *KubeConfig) SyncContexts(c *KubeConf, orphans string) ([]ContextChange, error) {
+	if err := validOrphans(orphans); err != nil {
+		return nil, err
+	}
+	changes := k.reconcile(c, orphans)
+	if _, err := os.Stat(c.ContextFile); len(changes) == 0 && err == nil {
+		return changes, nil
+	}
+	return changes, c.Save()
+}
+
+// reconcile applies the changes described in SyncContexts to the contexts of
+// c without writing them. The origins of c are replaced by the ones of the
+// kube config and of the stale contexts.
+func (k *KubeConfig) reconcile(c *KubeConf, orphans string) []ContextChange {
+	changes := []ContextChange{}
+
+	kubeContexts := map[string]*KubeContext{}
+	for i := range k.Contexts {
+		kubeContexts[k.Contexts[i].Name] = &k.Contexts[i]
+	}
+	known := map[string]bool{}
+	orphaned := []*ContextEntry{}
+	for _, entry := range c.Contexts {
+		ctx, exists := kubeContexts[entry.Name]
+		if !exists {
+			orphaned = append(orphaned, entry)
+			continue
+		}
+		known[ctx.Name] = true
+		if entry.Bool(staleKey) {
+			entry.Delete(staleKey)
+			changes = append(changes, ContextChange{Kind: ContextRestored, Name: ctx.Name})
+		}
+	}
+
+	for i := range k.Contexts {
+		ctx := &k.Contexts[i]
+		if known[ctx.Name] {
+			continue
+		}
+		if idx := renameCandidate(orphaned, c.origins, ctx); idx != -1 {
+			entry := orphaned[idx]
+			orphaned = append(orphaned[:idx], orphaned[idx+1:]...)
+			from := entry.Name
+			entry.Name = ctx.Name
+			entry.Delete(staleKey)
+			changes = append(changes, ContextChange{Kind: ContextRenamed, Name: ctx.Name, From: from})
+			continue
+		}
+		c.Contexts = append(c.Contexts, &ContextEntry{Name: ctx.Name, KubeConfig: k.Path})
+		changes = append(changes, ContextChange{Kind: ContextAdded, Name: ctx.Name})
+	}
+
+	origins := map[string]Origin{}
+	for _, ctx := range k.Contexts {
+		if ctx.Context != nil {
+			origins[ctx.Name] = Origin{Cluster: ctx.Cluster, User: ctx.User}
+		}
+	}
+	contexts := []*ContextEntry{}
+	for _, entry := range c.Contexts {
+		name := entry.Name
+		if _, exists := kubeContexts[name]; exists {
+			contexts = append(contexts, entry)
+			continue
+		}
+		if orphans == OrphansDelete {
+			changes = append(changes, ContextChange{Kind: ContextRemoved, Name: name})
+			continue
+		}
+		if !entry.Bool(staleKey) {
+			entry.Set(staleKey, true)
+			changes = append(changes, ContextChange{Kind: ContextStale, Name: name})
+		}
+		if o, exists := c.origins[name]; exists {
+			origins[name] = o
+		}
+		contexts = append(contexts, entry)
+	}
+	c.Contexts = contexts
+	c.origins = origins
+	return changes
+}
+
+// renameCandidate returns the index of the only orphan with the same cluster
+// and user as the context. Without or with multiple candidates -1 is
+// returned.
+func renameCandidate(orphaned []*ContextEntry, origins map[string]Origin, ctx *KubeContext) int {
+	if ctx.Context == nil || ctx.Cluster == "" {
+		return -1
+	}
+	candidate := -1
+	for idx, entry := range orphaned {
+		if o, exists := origins[entry.Name]; !exists || o.Cluster != ctx.Cluster || o.User != ctx.User {
+			continue
+		}
+		if candidate != -1 {
+			return -1
+		}
+		candidate = idx
+	}
+	return candidate
+}
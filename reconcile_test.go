@@ -0,0 +1,204 @@
+package k8sctx
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeConfig_SyncContexts_reconcile(t *testing.T) {
+	kubeConfig := &KubeConfig{
+		Path: "kube.config",
+		Contexts: []KubeContext{
+			{Name: "dev", Context: &Context{Cluster: "dev", User: "dev"}},
+			{Name: "prod-v2", Context: &Context{Cluster: "prod", User: "admin"}},
+			{Name: "lab-b", Context: &Context{Cluster: "lab", User: "lab"}},
+		},
+	}
+	entry := func(kv ...any) *ContextEntry {
+		c := &ContextEntry{}
+		for i := 0; i+1 < len(kv); i += 2 {
+			c.Set(kv[i].(string), kv[i+1])
+		}
+		return c
+	}
+	current := map[string]Origin{
+		"dev":     {Cluster: "dev", User: "dev"},
+		"prod-v2": {Cluster: "prod", User: "admin"},
+		"lab-b":   {Cluster: "lab", User: "lab"},
+	}
+	with := func(origins map[string]Origin, name string, o Origin) map[string]Origin {
+		m := maps.Clone(origins)
+		m[name] = o
+		return m
+	}
+	tests := []struct {
+		name        string
+		contexts    []*ContextEntry
+		origins     map[string]Origin
+		orphans     string
+		want        []*ContextEntry
+		wantOrigins map[string]Origin
+		wantChanges []ContextChange
+		wantErr     error
+	}{
+		{
+			name: "rename, stale and added",
+			contexts: []*ContextEntry{
+				entry("name", "dev", "alias", "d"),
+				entry("name", "prod", "alias", "p", "environment", "prod"),
+				entry("name", "gone"),
+			},
+			origins: map[string]Origin{
+				"dev":  {Cluster: "dev", User: "dev"},
+				"prod": {Cluster: "prod", User: "admin"},
+				"gone": {Cluster: "gone", User: "gone"},
+			},
+			want: []*ContextEntry{
+				entry("name", "dev", "alias", "d"),
+				entry("name", "prod-v2", "alias", "p", "environment", "prod"),
+				entry("name", "gone", staleKey, true),
+				entry("name", "lab-b", "kubeconfig", "kube.config"),
+			},
+			wantOrigins: with(current, "gone", Origin{Cluster: "gone", User: "gone"}),
+			wantChanges: []ContextChange{
+				{Kind: ContextRenamed, Name: "prod-v2", From: "prod"},
+				{Kind: ContextAdded, Name: "lab-b"},
+				{Kind: ContextStale, Name: "gone"},
+			},
+		},
+		{
+			name:    "delete orphans",
+			orphans: OrphansDelete,
+			contexts: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod-v2"),
+				entry("name", "lab-b"),
+				entry("name", "gone", staleKey, "true"),
+			},
+			origins: with(current, "gone", Origin{Cluster: "gone", User: "gone"}),
+			want: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod-v2"),
+				entry("name", "lab-b"),
+			},
+			wantOrigins: current,
+			wantChanges: []ContextChange{{Kind: ContextRemoved, Name: "gone"}},
+		},
+		{
+			name: "ambiguous rename",
+			contexts: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod-a"),
+				entry("name", "prod-b"),
+				entry("name", "lab-b"),
+			},
+			origins: map[string]Origin{
+				"dev":    {Cluster: "dev", User: "dev"},
+				"prod-a": {Cluster: "prod", User: "admin"},
+				"prod-b": {Cluster: "prod", User: "admin"},
+			},
+			want: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod-a", staleKey, true),
+				entry("name", "prod-b", staleKey, true),
+				entry("name", "lab-b"),
+				entry("name", "prod-v2", "kubeconfig", "kube.config"),
+			},
+			wantOrigins: with(with(current, "prod-a", Origin{Cluster: "prod", User: "admin"}),
+				"prod-b", Origin{Cluster: "prod", User: "admin"}),
+			wantChanges: []ContextChange{
+				{Kind: ContextAdded, Name: "prod-v2"},
+				{Kind: ContextStale, Name: "prod-a"},
+				{Kind: ContextStale, Name: "prod-b"},
+			},
+		},
+		{
+			name: "no rename without origin",
+			contexts: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod"),
+				entry("name", "lab-b"),
+			},
+			want: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod", staleKey, true),
+				entry("name", "lab-b"),
+				entry("name", "prod-v2", "kubeconfig", "kube.config"),
+			},
+			wantOrigins: current,
+			wantChanges: []ContextChange{
+				{Kind: ContextAdded, Name: "prod-v2"},
+				{Kind: ContextStale, Name: "prod"},
+			},
+		},
+		{
+			name: "restored and not stale",
+			contexts: []*ContextEntry{
+				entry("name", "dev", staleKey, "true"),
+				entry("name", "prod-v2", staleKey, false),
+				entry("name", "lab-b"),
+			},
+			want: []*ContextEntry{
+				entry("name", "dev"),
+				entry("name", "prod-v2", staleKey, false),
+				entry("name", "lab-b"),
+			},
+			wantOrigins: current,
+			wantChanges: []ContextChange{{Kind: ContextRestored, Name: "dev"}},
+		},
+		{
+			name:     "negative - unknown orphans setting",
+			orphans:  "keep",
+			contexts: []*ContextEntry{},
+			wantErr:  ErrOrphans,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &KubeConf{
+				ContextFile: filepath.Join(t.TempDir(), "contexts_t.yaml"),
+				Contexts:    tt.contexts,
+				origins:     tt.origins,
+			}
+			changes, err := kubeConfig.SyncContexts(c, tt.orphans)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantChanges, changes)
+			assert.Equal(t, tt.want, c.Contexts)
+			assert.Equal(t, tt.wantOrigins, c.origins)
+			assert.FileExists(t, c.ContextFile)
+		})
+	}
+}
+
+func TestKubeConfig_SyncContexts_unchanged(t *testing.T) {
+	kubeConfig := &KubeConfig{
+		Contexts: []KubeContext{{Name: "dev", Context: &Context{Cluster: "dev", User: "dev"}}},
+	}
+	c := &KubeConf{
+		ContextFile: filepath.Join(t.TempDir(), "contexts_t.yaml"),
+		Contexts:    []*ContextEntry{{Name: "dev"}},
+	}
+	if _, err := kubeConfig.SyncContexts(c, OrphansMark); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(c.ContextFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := kubeConfig.SyncContexts(c, OrphansMark)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	after, err := os.Stat(c.ContextFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, os.SameFile(before, after), "unchanged contexts file was written")
+}
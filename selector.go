@@ -0,0 +1,291 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrSelector is returned for an invalid context selector.
+var ErrSelector = errors.New("invalid context selector")
+
+var (
+	setRequirement   = regexp.MustCompile(`^([^\s=!(),]+)\s+(in|notin)\s*\((.*)\)$`)
+	fieldRequirement = regexp.MustCompile(`^([^\s=!(),]+)\s*(==|!=|=)\s*([^\s(),]*)$`)
+	absentKey        = regexp.MustCompile(`^!\s*([^\s=!(),]+)$`)
+)
+
+type (
+	// SelectedContext is a context chosen by a selector together with the
+	// kube config it belongs to.
+	SelectedContext struct {
+		KubeConf *KubeConf
+		Context  *ContextEntry
+	}
+	// requirement is a single term of a selector, like "region!=us-east-1".
+	// The operator is empty for the alias of a kube config or a glob for the
+	// name of a context.
+	requirement struct {
+		key      string
+		operator string
+		values   []string
+		// globs are the compiled values (see glob).
+		globs []*regexp.Regexp
+	}
+)
+
+// Name returns the alias of the context or its name, if it has no alias.
+func (s SelectedContext) Name() string {
+	return displayName(s.Context)
+}
+
+// QualifiedName returns the name of the context together with the alias of
+// its kube config, like "live/prod", which is unique across the kube configs.
+func (s SelectedContext) QualifiedName() string {
+	return s.KubeConf.QualifiedName(s.Name())
+}
+
+// IsSelector returns true if the term uses the operators of a label
+// selector, like "environment=prod" or "region notin (eu)", and is not only
+// a name or a glob.
+func IsSelector(term string) bool {
+	requirements, err := parseSelector([]string{term})
+	if err != nil {
+		return strings.ContainsAny(term, "=!(")
+	}
+	for _, r := range requirements {
+		if r.operator != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the contexts, which match all terms of the selector. The
+// terms follow the label selectors of Kubernetes and can be joined by
+// commas, like "environment in (prod,stage),region!=us-east-1". A term is
+// either
+//
+//   - a "key=value" or "key==value" pair, which matches the field of the
+//     contexts file, like "environment=prod". The key "config" matches the
+//     kube config alias.
+//   - a "key!=value" pair, which matches the contexts without the value.
+//   - a "key in (a,b)" or "key notin (a,b)" set of values.
+//   - a "!key", which matches the contexts without the field.
+//   - the alias of a kube config, which matches all its contexts.
+//   - a glob, like "prod-*", which matches the name or alias of the context.
+//
+// The values can be globs, too. Without terms all contexts are selected.
+func (c *Config) Select(selector []string) ([]SelectedContext, error) {
+	requirements, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	selected := []SelectedContext{}
+	for _, cnf := range c.KubeConfs {
+		for _, ctx := range cnf.Contexts {
+			s := SelectedContext{KubeConf: cnf, Context: ctx}
+			if s.matches(requirements) {
+				selected = append(selected, s)
+			}
+		}
+	}
+	return selected, nil
+}
+
+// SelectOne returns the only context, which matches the selector. It fails
+// with ErrAmbiguousContext, if more than one context matches.
+func (c *Config) SelectOne(selector []string) (SelectedContext, error) {
+	selected, err := c.Select(selector)
+	if err != nil {
+		return SelectedContext{}, err
+	}
+	switch len(selected) {
+	case 0:
+		return SelectedContext{}, fmt.Errorf("%w: '%s'", ErrNoContext, strings.Join(selector, " "))
+	case 1:
+		return selected[0], nil
+	}
+	names := make([]string, len(selected))
+	for idx, s := range selected {
+		names[idx] = s.KubeConf.QualifiedName(s.Name())
+	}
+	return SelectedContext{}, fmt.Errorf("%w: '%s', matches: %s",
+		ErrAmbiguousContext, strings.Join(selector, " "), strings.Join(names, ", "))
+}
+
+// SelectListItems returns the list items of the contexts, which match the
+// selector (see Select).
+func (c *Config) SelectListItems(selector []string) ([]ContextItem, error) {
+	selected, err := c.Select(selector)
+	if err != nil {
+		return nil, err
+	}
+	chosen := map[[2]string]bool{}
+	for _, s := range selected {
+		chosen[[2]string{s.KubeConf.Alias, s.Context.Name}] = true
+	}
+	items := []ContextItem{}
+	for _, i := range c.CreateListItems("", "") {
+		if chosen[[2]string{i.Config, i.Context}] {
+			items = append(items, i)
+		}
+	}
+	return items, nil
+}
+
+// parseSelector parses and validates the terms of the selector.
+func parseSelector(selector []string) ([]requirement, error) {
+	requirements := []requirement{}
+	for _, arg := range selector {
+		for _, term := range splitTerms(arg) {
+			r, err := parseRequirement(strings.TrimSpace(term))
+			if err != nil {
+				return nil, fmt.Errorf("%w '%s': %w", ErrSelector, arg, err)
+			}
+			requirements = append(requirements, r)
+		}
+	}
+	return requirements, nil
+}
+
+// splitTerms splits the selector at the commas outside of parentheses.
+func splitTerms(selector string) []string {
+	terms := []string{}
+	depth, start := 0, 0
+	for idx, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:idx])
+				start = idx + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+// parseRequirement parses a single term of a selector.
+func parseRequirement(term string) (requirement, error) {
+	var r requirement
+	switch {
+	case term == "":
+		return r, errors.New("empty term")
+	case setRequirement.MatchString(term):
+		m := setRequirement.FindStringSubmatch(term)
+		r = requirement{key: m[1], operator: m[2]}
+		for _, v := range strings.Split(m[3], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				r.values = append(r.values, v)
+			}
+		}
+		if len(r.values) == 0 {
+			return r, errors.New("empty set of values")
+		}
+	case fieldRequirement.MatchString(term):
+		m := fieldRequirement.FindStringSubmatch(term)
+		r = requirement{key: m[1], operator: strings.TrimPrefix(m[2], "="), values: []string{m[3]}}
+		if r.operator == "" {
+			r.operator = "="
+		}
+	case absentKey.MatchString(term):
+		r = requirement{key: absentKey.FindStringSubmatch(term)[1], operator: "!"}
+	case strings.ContainsAny(term, "=!(), \t"):
+		return r, errors.New("unknown operator")
+	default:
+		r = requirement{values: []string{term}}
+	}
+	for _, v := range r.values {
+		g, err := glob(v)
+		if err != nil {
+			return r, err
+		}
+		r.globs = append(r.globs, g)
+	}
+	return r, nil
+}
+
+// glob compiles the pattern into an anchored regular expression. The syntax
+// is the one of path.Match, but "*" and "?" match "/" as well, so that
+// "*/prod" matches a name like "arn:aws:eks:eu-west-1:1234:cluster/prod".
+func glob(pattern string) (*regexp.Regexp, error) {
+	expr := &strings.Builder{}
+	expr.WriteString("^")
+	for idx := 0; idx < len(pattern); idx++ {
+		switch pattern[idx] {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if idx++; idx == len(pattern) {
+				return nil, path.ErrBadPattern
+			}
+			expr.WriteString(regexp.QuoteMeta(pattern[idx : idx+1]))
+		case '[':
+			end := strings.IndexByte(pattern[idx+1:], ']')
+			if end < 1 {
+				return nil, path.ErrBadPattern
+			}
+			class := pattern[idx+1 : idx+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			idx += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[idx : idx+1]))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", path.ErrBadPattern, err)
+	}
+	return re, nil
+}
+
+// matches returns true if the context matches all requirements.
+func (s SelectedContext) matches(requirements []requirement) bool {
+	for _, r := range requirements {
+		if !s.matchesRequirement(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s SelectedContext) matchesRequirement(r requirement) bool {
+	if r.operator == "" {
+		if r.values[0] == s.KubeConf.Alias {
+			return true
+		}
+		return r.globs[0].MatchString(s.Context.Name) || r.globs[0].MatchString(s.Name())
+	}
+	field, exists := s.Context.Field(r.key), s.Context.Has(r.key)
+	if r.key == "config" {
+		field, exists = s.KubeConf.Alias, true
+	}
+	matched := false
+	for _, g := range r.globs {
+		if g.MatchString(field) {
+			matched = true
+			break
+		}
+	}
+	switch r.operator {
+	case "=", "in":
+		return exists && matched
+	case "!=", "notin":
+		return !exists || !matched
+	case "!":
+		return !exists
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package k8sctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func selectorConfig() *Config {
+	return &Config{
+		KubeConfs: []*KubeConf{
+			{
+				Alias: "lab",
+				Contexts: []*ContextEntry{
+					{Name: "lab-eu", Metadata: map[string]any{"environment": "dev", "region": "eu"}},
+					{Name: "cluster-lab-us", Alias: "lab-us", Metadata: map[string]any{"environment": "dev", "region": "us"}},
+				},
+			},
+			{
+				Alias: "live",
+				Contexts: []*ContextEntry{
+					{Name: "prod-eu", Metadata: map[string]any{"environment": "prod", "region": "eu"}},
+					{Name: "prod-us", Metadata: map[string]any{"environment": "prod", "region": "us"}},
+					{Name: "stage-eu"},
+				},
+			},
+		},
+	}
+}
+
+func TestConfig_Select(t *testing.T) {
+	c := selectorConfig()
+	tests := []struct {
+		name     string
+		selector []string
+		want     []string
+		wantErr  error
+	}{
+		{name: "all", selector: nil, want: []string{"lab-eu", "lab-us", "prod-eu", "prod-us", "stage-eu"}},
+		{name: "glob on name", selector: []string{"prod-*"}, want: []string{"prod-eu", "prod-us"}},
+		{name: "glob on alias", selector: []string{"*-us"}, want: []string{"lab-us", "prod-us"}},
+		{name: "kube config alias", selector: []string{"lab"}, want: []string{"lab-eu", "lab-us"}},
+		{name: "field", selector: []string{"environment=prod"}, want: []string{"prod-eu", "prod-us"}},
+		{name: "config field", selector: []string{"config=live"}, want: []string{"prod-eu", "prod-us", "stage-eu"}},
+		{name: "all terms match", selector: []string{"environment=*", "region=eu"}, want: []string{"lab-eu", "prod-eu"}},
+		{name: "missing field", selector: []string{"team=*"}, want: []string{}},
+		{name: "double equal", selector: []string{"environment==dev"}, want: []string{"lab-eu", "lab-us"}},
+		{name: "not equal", selector: []string{"region!=us"}, want: []string{"lab-eu", "prod-eu", "stage-eu"}},
+		{name: "in", selector: []string{"environment in (prod, stage)"}, want: []string{"prod-eu", "prod-us"}},
+		{name: "notin", selector: []string{"environment notin (prod)"}, want: []string{"lab-eu", "lab-us", "stage-eu"}},
+		{name: "absent field", selector: []string{"!environment"}, want: []string{"stage-eu"}},
+		{
+			name:     "comma separated",
+			selector: []string{"environment in (prod,dev),region!=us"},
+			want:     []string{"lab-eu", "prod-eu"},
+		},
+		{name: "comma separated with glob", selector: []string{"config=live,*-eu"}, want: []string{"prod-eu", "stage-eu"}},
+		{name: "negative - bad pattern", selector: []string{"prod-["}, wantErr: ErrSelector},
+		{name: "negative - unknown operator", selector: []string{"environment prod"}, wantErr: ErrSelector},
+		{name: "negative - empty set", selector: []string{"environment in ()"}, wantErr: ErrSelector},
+		{name: "negative - empty term", selector: []string{"environment=prod,"}, wantErr: ErrSelector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Select(tt.selector)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			names := []string{}
+			for _, s := range got {
+				names = append(names, s.Name())
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestConfig_Select_arn(t *testing.T) {
+	c := &Config{
+		KubeConfs: []*KubeConf{{
+			Alias: "eks",
+			Contexts: []*ContextEntry{
+				{Name: "arn:aws:eks:eu-west-1:1234:cluster/prod"},
+				{Name: "arn:aws:eks:eu-west-1:1234:cluster/dev", Metadata: map[string]any{"cluster": "arn:aws:eks:eu-west-1:1234:cluster/dev"}},
+				{Name: "minikube"},
+			},
+		}},
+	}
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "arn:*", want: []string{"arn:aws:eks:eu-west-1:1234:cluster/prod", "arn:aws:eks:eu-west-1:1234:cluster/dev"}},
+		{selector: "*prod", want: []string{"arn:aws:eks:eu-west-1:1234:cluster/prod"}},
+		{selector: "*/prod", want: []string{"arn:aws:eks:eu-west-1:1234:cluster/prod"}},
+		{selector: "arn:aws:eks:eu-west-1:1234:cluster?prod", want: []string{"arn:aws:eks:eu-west-1:1234:cluster/prod"}},
+		{selector: "cluster=*/dev", want: []string{"arn:aws:eks:eu-west-1:1234:cluster/dev"}},
+		{selector: "*[^/]kube", want: []string{"minikube"}},
+		{selector: "arn:aws:eks:eu-west-1:1234:cluster/prod*\\*", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := c.Select([]string{tt.selector})
+			assert.NoError(t, err)
+			names := []string{}
+			for _, s := range got {
+				names = append(names, s.Name())
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestConfig_SelectOne(t *testing.T) {
+	c := selectorConfig()
+	tests := []struct {
+		name     string
+		selector []string
+		want     string
+		wantErr  error
+	}{
+		{name: "single match", selector: []string{"environment=prod,region=us"}, want: "prod-us"},
+		{name: "negative - no match", selector: []string{"environment=qa"}, wantErr: ErrNoContext},
+		{name: "negative - ambiguous", selector: []string{"environment=prod"}, wantErr: ErrAmbiguousContext},
+		{name: "negative - invalid", selector: []string{"region in (eu"}, wantErr: ErrSelector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.SelectOne(tt.selector)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Name())
+		})
+	}
+}
+
+func TestConfig_SelectListItems(t *testing.T) {
+	items, err := selectorConfig().SelectListItems([]string{"region=us"})
+	assert.NoError(t, err)
+	names := []string{}
+	for _, i := range items {
+		names = append(names, i.Name)
+	}
+	assert.Equal(t, []string{"lab-us", "prod-us"}, names)
+}
+
+func TestIsSelector(t *testing.T) {
+	tests := []struct {
+		term string
+		want bool
+	}{
+		{term: "lab", want: false},
+		{term: "prod-*", want: false},
+		{term: "environment=prod", want: true},
+		{term: "region!=us", want: true},
+		{term: "environment in (prod,stage)", want: true},
+		{term: "!environment", want: true},
+		{term: "environment>prod", want: false},
+		{term: "environment in (prod", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsSelector(tt.term))
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package k8sctx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SessionEnv is the environment variable holding the id of the session.
+	SessionEnv = "KTX_SESSION"
+	// sessionDir is the folder inside the config dir for the session files.
+	sessionDir = "sessions"
+)
+
+// ErrSession is returned if the context of a session cannot be changed.
+var ErrSession = errors.New("failed to switch context of session")
+
+type (
+	// Session changes the context only for a single shell. Instead of
+	// changing the current-context inside the kube config files, a small
+	// session kube config is written, which must be the first file in the
+	// KUBECONFIG environment variable of the shell.
+	Session struct {
+		// ID of the session, usually taken from the KTX_SESSION variable.
+		ID string
+		// KubeConfig is the path of the session kube config.
+		KubeConfig string
+		// State of the session, which is used to jump back via "ktx -".
+		*State
+		// PID is the process id of the shell of the session. If set, it is
+		// stored in the state of the session (see PruneSessions).
+		PID int
+		// lock of the config dir, which is shared with the Config.
+		lock *dirLock
+	}
+	// Switcher changes the current context and keeps track of the previous
+	// one in its state. It is implemented by the Config, which changes the
+	// kube config files, and by a Session.
+	Switcher interface {
+		GetState() error
+		SwitchTo(k *KubeConf, contextName string) error
+		SwitchToNamespace(k *KubeConf, contextName, namespace string) error
+	}
+	// sessionKubeConfig is the content of the session kube config. It holds
+	// the current context and a copy of the context with the namespace. The
+	// clusters and users are taken from the other kube configs.
+	sessionKubeConfig struct {
+		APIVersion     string        `yaml:"apiVersion"`
+		Kind           string        `yaml:"kind"`
+		CurrentContext string        `yaml:"current-context"`
+		Contexts       []KubeContext `yaml:"contexts"`
+	}
+)
+
+// NewSessionID returns a random id for a new session.
+func NewSessionID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Session returns the session with the given id. The session files are
+// stored in the "sessions" folder of the config dir.
+func (c *Config) Session(id string) *Session {
+	dir := filepath.Join(c.Dir, sessionDir)
+	return &Session{
+		ID:         id,
+		KubeConfig: filepath.Join(dir, id+".kubeconfig"),
+		State:      &State{Filename: filepath.Join(dir, id+".state")},
+		lock:       c.lock,
+	}
+}
+
+// IsSessionFile returns true if the path belongs to the session files.
+func (c *Config) IsSessionFile(path string) bool {
+	return filepath.Dir(filepath.Clean(path)) == filepath.Join(c.Dir, sessionDir)
+}
+
+// GetState reads the state file of the session.
+func (s *Session) GetState() error {
+	return s.State.load()
+}
+
+// SwitchTo writes the session kube config with the given context of the
+// kube config k as current context and updates the state of the session.
+// The kube config files themselves are not changed.
+func (s *Session) SwitchTo(k *KubeConf, contextName string) error {
+	return s.SwitchToNamespace(k, contextName, "")
+}
+
+// SwitchToNamespace works like SwitchTo and sets in addition the namespace of
+// the context inside the session kube config. Without a namespace, the last
+// used namespace of the context in this session is used, if any.
+func (s *Session) SwitchToNamespace(k *KubeConf, contextName, namespace string) error {
+	if err := s.switchTo(k, contextName, namespace); err != nil {
+		return fmt.Errorf("%w '%s': %w", ErrSession, contextName, err)
+	}
+	return nil
+}
+
+func (s *Session) switchTo(k *KubeConf, contextName, namespace string) error {
+	ctx, _, err := k.KubeConfig.GetContextBy(contextName)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.KubeConfig)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	unlock, err := s.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	prev, err := os.ReadFile(s.KubeConfig)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	state := *s.State
+	if err := s.State.load(); err != nil {
+		*s.State = state
+		return err
+	}
+	if s.PID != 0 {
+		s.State.ShellPID = s.PID
+	}
+	if namespace == "" {
+		namespace = s.State.Namespace(k.Path, contextName)
+	}
+	if namespace == "" {
+		namespace = ctx.Namespace
+	}
+	// the namespace of the context, which is left, may have been changed
+	// inside the session
+	if prev != nil {
+		current := &sessionKubeConfig{}
+		if err := yaml.Unmarshal(prev, current); err == nil {
+			for _, c := range current.Contexts {
+				if c.Name == current.CurrentContext && c.Context != nil {
+					s.State.leave(c.Namespace)
+				}
+			}
+		}
+	}
+	sctx := *ctx.Context
+	sctx.Namespace = namespace
+
+	cnf, err := yaml.Marshal(&sessionKubeConfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: contextName,
+		Contexts:       []KubeContext{{Name: ctx.Name, Context: &sctx}},
+	})
+	if err != nil {
+		*s.State = state
+		return err
+	}
+	s.State.update(k.Path, contextName, namespace)
+	st, err := yaml.Marshal(s.State)
+	if err != nil {
+		*s.State = state
+		return err
+	}
+
+	if err := replaceFile(s.KubeConfig, cnf, FileMode); err != nil {
+		*s.State = state
+		return err
+	}
+	if err := replaceFile(s.Filename, st, FileMode); err != nil {
+		*s.State = state
+		if prev == nil {
+			return errors.Join(err, os.Remove(s.KubeConfig))
+		}
+		return errors.Join(err, replaceFile(s.KubeConfig, prev, FileMode))
+	}
+	return nil
+}
+
+// Remove deletes the files of the session.
+func (s *Session) Remove() error {
+	unlock, err := s.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var errs []error
+	for _, f := range []string{s.KubeConfig, s.Filename} {
+		if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PruneSessions removes the files of the sessions, whose shell exited without
+// ending the session. A session without the process id of its shell is
+// removed, if its files weren't changed for maxAge.
+func (c *Config) PruneSessions(maxAge time.Duration) error {
+	dir := filepath.Join(c.Dir, sessionDir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	unlock, err := c.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	latest := map[string]time.Time{}
+	for _, e := range entries {
+		id := strings.TrimSuffix(strings.TrimSuffix(e.Name(), ".kubeconfig"), ".state")
+		info, err := e.Info()
+		if err != nil || id == e.Name() {
+			continue
+		}
+		if t := info.ModTime(); t.After(latest[id]) {
+			latest[id] = t
+		}
+	}
+	var errs []error
+	for id, changed := range latest {
+		s := c.Session(id)
+		if err := s.GetState(); err != nil {
+			continue
+		}
+		if s.ShellPID != 0 && processAlive(s.ShellPID) {
+			continue
+		}
+		if s.ShellPID == 0 && time.Since(changed) < maxAge {
+			continue
+		}
+		if err := s.Remove(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
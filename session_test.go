@@ -0,0 +1,144 @@
+package k8sctx
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSession_SwitchTo(t *testing.T) {
+	t.Parallel()
+	c, files := switchSetup(t)
+	s := c.Session("test")
+
+	if err := s.SwitchTo(c.KubeConfs[1], "b1"); err != nil {
+		t.Fatalf("Session.SwitchTo() error = %v", err)
+	}
+	if err := s.SwitchTo(c.KubeConfs[1], "b2"); err != nil {
+		t.Fatalf("Session.SwitchTo() error = %v", err)
+	}
+	assert.ErrorIs(t, s.SwitchTo(c.KubeConfs[0], "b1"), ErrNoContext)
+
+	for _, name := range []string{"a", "b", "c", ".state"} {
+		got, err := os.ReadFile(filepath.Join(c.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, string(files[name]), string(got), "%s was changed", name)
+	}
+
+	got := &sessionKubeConfig{}
+	cnf, err := os.ReadFile(s.KubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(cnf, got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "b2", got.CurrentContext)
+	assert.Equal(t, []KubeContext{{Name: "b2", Context: &Context{Cluster: "b", User: "b"}}}, got.Contexts)
+
+	state := c.Session("test")
+	if err := state.GetState(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "b2", state.CurrentContext)
+	assert.Equal(t, "b1", state.LastContext)
+	assert.True(t, c.IsSessionFile(s.KubeConfig))
+
+	if err := s.Remove(); err != nil {
+		t.Fatalf("Session.Remove() error = %v", err)
+	}
+	assert.NoFileExists(t, s.KubeConfig)
+	assert.NoFileExists(t, s.Filename)
+}
+
+func TestSession_SwitchToNamespace(t *testing.T) {
+	t.Parallel()
+	c, files := switchSetup(t)
+	s := c.Session("test")
+	b := c.KubeConfs[1]
+
+	if err := s.SwitchToNamespace(b, "b1", "web"); err != nil {
+		t.Fatalf("Session.SwitchToNamespace() error = %v", err)
+	}
+	// the namespace is changed inside the session, like "ktx ns" does
+	k, err := GetKubeConfig(s.KubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddNamespaceTo("b1", "monitoring"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SwitchTo(b, "b2"); err != nil {
+		t.Fatalf("Session.SwitchTo() error = %v", err)
+	}
+	assert.Equal(t, "monitoring", s.LastNamespace)
+	assert.Equal(t, "", s.CurrentNamespace)
+
+	// back to b1 with its last used namespace
+	if err := s.SwitchTo(b, "b1"); err != nil {
+		t.Fatalf("Session.SwitchTo() error = %v", err)
+	}
+	assert.Equal(t, "monitoring", s.CurrentNamespace)
+	k, err = GetKubeConfig(s.KubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _, err := k.GetContextBy("b1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "monitoring", ctx.Namespace)
+	}
+
+	got, err := os.ReadFile(filepath.Join(c.Dir, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(files["b"]), string(got), "kube config was changed")
+}
+
+func TestConfig_PruneSessions(t *testing.T) {
+	t.Parallel()
+	c, _ := switchSetup(t)
+	// the process id of a shell, which already exited
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	sessions := map[string]int{
+		"alive":  os.Getpid(),
+		"exited": exited.Process.Pid,
+		"recent": 0,
+		"old":    0,
+	}
+	for id, pid := range sessions {
+		s := c.Session(id)
+		s.PID = pid
+		if err := s.SwitchTo(c.KubeConfs[0], "a1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	for _, f := range []string{c.Session("old").KubeConfig, c.Session("old").Filename} {
+		if err := os.Chtimes(f, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.NoError(t, c.PruneSessions(time.Hour))
+	for id, want := range map[string]bool{"alive": true, "exited": false, "recent": true, "old": false} {
+		s := c.Session(id)
+		if want {
+			assert.FileExists(t, s.KubeConfig, id)
+			assert.FileExists(t, s.Filename, id)
+		} else {
+			assert.NoFileExists(t, s.KubeConfig, id)
+			assert.NoFileExists(t, s.Filename, id)
+		}
+	}
+}
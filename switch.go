@@ -0,0 +1,277 @@
+package k8sctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrSwitchContext is returned if a context switch failed. In this case the
+// kube configs and the .state file are left as they were before. Errors after
+// the switch was written, e.g. while storing the fingerprint of the input
+// files, are returned without it.
+var ErrSwitchContext = errors.New("failed to switch context")
+
+// commitFile writes the prepared content of a single file during a switch.
+// The caller must hold the lock of the config dir.
+var commitFile = replaceFile
+
+// change holds the previous and the new content of a kube config or of a
+// contexts file, which is part of a context switch.
+type change struct {
+	path       string
+	kubeConfig *KubeConfig
+	current    string
+	// context is the name of the context, whose namespace was changed from
+	// namespace.
+	context   string
+	namespace string
+	// entry is the context in the contexts file, whose namespace was changed
+	// from namespace. It is only set for a contexts file.
+	entry *ContextEntry
+	// created is true, if the file didn't exist before.
+	created bool
+	prev    []byte
+	next    []byte
+}
+
+// SwitchTo sets the context with the given name as current context of the
+// kube config k, removes the current context from all other kube configs and
+// updates the .state file. The last used namespace of the context is set
+// again (see SwitchToNamespace).
+func (c *Config) SwitchTo(k *KubeConf, contextName string) error {
+	return c.SwitchToNamespace(k, contextName, "")
+}
+
+// SwitchToNamespace works like SwitchTo and sets in addition the namespace of
+// the context. Without a namespace, the last used namespace of the context
+// stored in the .state file is used, if any. The switch is a single
+// transaction: kube configs which already have the desired current context
+// are not written at all and if writing one of the files fails, all already
+// written files get their previous content back. A namespace, which is also
+// set in the contexts file, is updated there within the same transaction, so
+// that the next sync doesn't revert it. The switch itself doesn't cause
+// a sync (see KeepSynced).
+func (c *Config) SwitchToNamespace(k *KubeConf, contextName, namespace string) error {
+	switched := false
+	err := c.KeepSynced(func() (bool, error) {
+		written, err := c.switchTo(k, contextName, namespace)
+		switched = err == nil || written
+		return written, err
+	})
+	switch {
+	case err == nil:
+		return nil
+	case switched:
+		return fmt.Errorf("switched to context '%s', but: %w", contextName, err)
+	}
+	return fmt.Errorf("%w '%s': %w", ErrSwitchContext, contextName, err)
+}
+
+// switchTo does the switch of SwitchToNamespace and returns true, if one of
+// the kube configs or the contexts file was written. An error without a written file means that
+// the switch was rolled back.
+func (c *Config) switchTo(k *KubeConf, contextName, namespace string) (bool, error) {
+	statePath, err := resolveLinks(c.Filename)
+	if err != nil {
+		return false, err
+	}
+	paths := []string{statePath}
+	for _, kc := range c.KubeConfs {
+		path, err := resolveLinks(kc.KubeConfig.Path)
+		if err != nil {
+			return false, err
+		}
+		paths = append(paths, path)
+	}
+	unlock, err := c.Lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	state := *c.State
+	if err := c.GetState(); err != nil {
+		*c.State = state
+		return false, err
+	}
+	if namespace == "" {
+		namespace = c.State.Namespace(k.Path, contextName)
+	}
+
+	changes := []*change{}
+	// changed is the namespace of the context, if it differs from the one in
+	// the kube config
+	changed := ""
+	fail := func(err error) error {
+		*c.State = state
+		return errors.Join(err, restore(changes))
+	}
+	for idx, kc := range c.KubeConfs {
+		// read again, in case the file was changed since it was loaded
+		if kc.KubeConfig.doc != nil {
+			if err := kc.KubeConfig.Read(); err != nil {
+				return false, fail(err)
+			}
+		}
+		if kc.Path == c.State.CurrentConf {
+			c.State.leave(kc.namespaceOf(c.State.CurrentContext))
+		}
+		current, ns := "", ""
+		if kc == k {
+			kctx, _, err := kc.KubeConfig.GetContextBy(contextName)
+			if err != nil {
+				return false, fail(err)
+			}
+			current = contextName
+			if kctx.Namespace != namespace {
+				ns, changed = namespace, namespace
+			}
+		}
+		if kc.KubeConfig.CurrentContext == current && ns == "" {
+			continue
+		}
+		ch, err := kc.KubeConfig.prepare(current, ns)
+		if err != nil {
+			return false, fail(err)
+		}
+		ch.path = paths[idx+1]
+		changes = append(changes, ch)
+	}
+
+	ch, err := k.prepareNamespace(contextName, changed)
+	if err != nil {
+		return false, fail(err)
+	}
+	if ch != nil {
+		changes = append(changes, ch)
+	}
+
+	c.State.update(k.Path, contextName, k.namespaceOf(contextName))
+	c.State.track(c.Terminal)
+	cnf, err := yaml.Marshal(&c.State)
+	if err != nil {
+		return false, fail(err)
+	}
+
+	for idx, ch := range changes {
+		if err := commitFile(ch.path, ch.next, FileMode); err != nil {
+			return false, fail(errors.Join(err, rollback(changes[:idx])))
+		}
+	}
+	if err := commitFile(statePath, cnf, FileMode); err != nil {
+		return false, fail(errors.Join(err, rollback(changes)))
+	}
+	for _, ch := range changes {
+		if ch.kubeConfig == nil {
+			continue
+		}
+		if err := ch.kubeConfig.written(ch.next); err != nil {
+			return true, err
+		}
+	}
+	return len(changes) > 0, nil
+}
+
+// prepare renders the content of the kube config with the given current
+// context and, if set, the namespace of it. The previous content is kept
+// as read from disk for a rollback.
+func (k *KubeConfig) prepare(current, namespace string) (*change, error) {
+	prev := k.raw
+	if k.doc == nil {
+		var err error
+		if prev, err = k.render(); err != nil {
+			return nil, err
+		}
+	}
+	ch := &change{kubeConfig: k, current: k.CurrentContext, prev: prev}
+	k.CurrentContext = current
+	if namespace != "" {
+		ctx, idx, err := k.GetContextBy(current)
+		if err != nil {
+			k.CurrentContext = ch.current
+			return nil, err
+		}
+		ch.context, ch.namespace = current, ctx.Namespace
+		ctx.Namespace = namespace
+		k.Contexts[idx] = *ctx
+	}
+	next, err := k.render()
+	if err != nil {
+		return nil, errors.Join(err, ch.reset())
+	}
+	ch.next = next
+	return ch, nil
+}
+
+// prepareNamespace renders the contexts file with the namespace of the
+// context, if the context has a namespace there, so that the next sync
+// doesn't revert it. The previous content is kept for a rollback.
+func (k *KubeConf) prepareNamespace(contextName, namespace string) (*change, error) {
+	if namespace == "" {
+		return nil, nil
+	}
+	entry, idx := k.GetContextBy(contextName)
+	if idx == -1 || entry.Namespace == "" || entry.Namespace == namespace {
+		return nil, nil
+	}
+	path, err := resolveLinks(k.ContextFile)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ch := &change{path: path, entry: entry, namespace: entry.Namespace, created: err != nil, prev: prev}
+	entry.Namespace = namespace
+	if ch.next, err = yaml.Marshal(&k.Contexts); err != nil {
+		return nil, errors.Join(err, ch.reset())
+	}
+	return ch, nil
+}
+
+// reset sets the current context and the namespace of the kube config in
+// memory back to their previous values.
+func (ch *change) reset() error {
+	if ch.entry != nil {
+		ch.entry.Namespace = ch.namespace
+		return nil
+	}
+	ch.kubeConfig.CurrentContext = ch.current
+	if ch.context != "" {
+		if ctx, _, err := ch.kubeConfig.GetContextBy(ch.context); err == nil {
+			ctx.Namespace = ch.namespace
+		}
+	}
+	return ch.kubeConfig.written(ch.prev)
+}
+
+// rollback writes the previous content of the already written files back to
+// disk.
+func rollback(changes []*change) error {
+	var errs []error
+	for _, ch := range changes {
+		var err error
+		if ch.created {
+			err = os.Remove(ch.path)
+		} else {
+			err = commitFile(ch.path, ch.prev, FileMode)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("restore '%s': %w", ch.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restore resets the kube configs in memory to their previous content.
+func restore(changes []*change) error {
+	var errs []error
+	for _, ch := range changes {
+		errs = append(errs, ch.reset())
+	}
+	return errors.Join(errs...)
+}
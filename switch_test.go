@@ -0,0 +1,278 @@
+package k8sctx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// switchSetup creates three kube configs and a .state file in a temporary
+// directory. The first kube config "a" holds the current context.
+func switchSetup(t *testing.T) (*Config, map[string][]byte) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a":      []byte("contexts:\n- name: a1\n  context:\n    cluster: a\n    user: a\ncurrent-context: a1\n"),
+		"b":      []byte("# without a current context\ncontexts:\n- name: b1\n  context:\n    cluster: b\n    user: b\n- name: b2\n  context:\n    cluster: b\n    user: b\n"),
+		"c":      []byte("contexts:\n- name: c1\n  context:\n    cluster: c\n    user: c\ncurrent-context: \"\"\n"),
+		".state": []byte("currentKubeConfig: " + filepath.Join(dir, "a") + "\ncurrentContext: a1\n"),
+	}
+	c := &Config{Dir: dir, State: &State{Filename: filepath.Join(dir, ".state")}}
+	defer c.setLock(newDirLock(dir))
+	for _, name := range []string{"a", "b", "c", ".state"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, files[name], 0600); err != nil {
+			t.Fatal(err)
+		}
+		if name == ".state" {
+			continue
+		}
+		k, err := GetKubeConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.KubeConfs = append(c.KubeConfs, &KubeConf{Path: path, Alias: name, KubeConfig: k})
+	}
+	return c, files
+}
+
+func TestConfig_SwitchTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		kubeConf    int
+		contextName string
+		failOn      string
+		wantErr     error
+		wantCurrent map[string]string
+	}{
+		{
+			name:        "positive",
+			kubeConf:    1,
+			contextName: "b2",
+			wantCurrent: map[string]string{"a": "", "b": "b2", "c": ""},
+		},
+		{
+			name:        "negative - unknown context",
+			kubeConf:    1,
+			contextName: "a1",
+			wantErr:     ErrNoContext,
+			wantCurrent: map[string]string{"a": "a1", "b": "", "c": ""},
+		},
+		{
+			name:        "negative - rollback on kube config",
+			kubeConf:    1,
+			contextName: "b2",
+			failOn:      "b",
+			wantErr:     ErrSwitchContext,
+			wantCurrent: map[string]string{"a": "a1", "b": "", "c": ""},
+		},
+		{
+			name:        "negative - rollback on state",
+			kubeConf:    1,
+			contextName: "b2",
+			failOn:      ".state",
+			wantErr:     ErrSwitchContext,
+			wantCurrent: map[string]string{"a": "a1", "b": "", "c": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, files := switchSetup(t)
+			before, err := os.Stat(filepath.Join(c.Dir, "c"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.failOn != "" {
+				commitFile = func(path string, data []byte, perm os.FileMode) error {
+					if filepath.Base(path) == tt.failOn {
+						return errors.New("disk full")
+					}
+					return replaceFile(path, data, perm)
+				}
+				t.Cleanup(func() { commitFile = replaceFile })
+			}
+
+			err = c.SwitchTo(c.KubeConfs[tt.kubeConf], tt.contextName)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for _, kc := range c.KubeConfs {
+				assert.Equal(t, tt.wantCurrent[kc.Alias], kc.KubeConfig.CurrentContext, "in memory: %s", kc.Alias)
+				got, err := GetKubeConfig(kc.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantCurrent[kc.Alias], got.CurrentContext, "on disk: %s", kc.Alias)
+			}
+			after, err := os.Stat(filepath.Join(c.Dir, "c"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, os.SameFile(before, after), "unchanged kube config was written")
+
+			state, err := os.ReadFile(c.Filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil {
+				assert.Equal(t, string(files[".state"]), string(state))
+				for _, kc := range c.KubeConfs {
+					got, err := os.ReadFile(kc.Path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assert.Equal(t, string(files[kc.Alias]), string(got), "restored: %s", kc.Alias)
+				}
+				return
+			}
+			assert.Equal(t, c.KubeConfs[tt.kubeConf].Path, c.CurrentConf)
+			assert.Equal(t, tt.contextName, c.CurrentContext)
+			assert.Equal(t, c.KubeConfs[0].Path, c.LastConf)
+			assert.Equal(t, "a1", c.LastContext)
+		})
+	}
+}
+
+func TestConfig_SwitchToNamespace(t *testing.T) {
+	c, _ := switchSetup(t)
+	a, b := c.KubeConfs[0], c.KubeConfs[1]
+	namespaceOf := func(path, context string) string {
+		t.Helper()
+		k, err := GetKubeConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, _, err := k.GetContextBy(context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ctx.Namespace
+	}
+
+	if err := c.SwitchToNamespace(b, "b2", "web"); err != nil {
+		t.Fatalf("Config.SwitchToNamespace() error = %v", err)
+	}
+	assert.Equal(t, "web", namespaceOf(b.Path, "b2"))
+	assert.Equal(t, "web", c.CurrentNamespace)
+
+	// the namespace was changed outside of ktx, while b2 was the current context
+	if err := b.KubeConfig.AddNamespaceTo("b2", "monitoring"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SwitchTo(a, "a1"); err != nil {
+		t.Fatalf("Config.SwitchTo() error = %v", err)
+	}
+	assert.Equal(t, "monitoring", c.LastNamespace)
+	assert.Equal(t, "monitoring", c.Namespace(b.Path, "b2"))
+
+	// back to b2 with its last used namespace
+	if err := b.KubeConfig.AddNamespaceTo("b2", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SwitchTo(b, "b2"); err != nil {
+		t.Fatalf("Config.SwitchTo() error = %v", err)
+	}
+	assert.Equal(t, "monitoring", namespaceOf(b.Path, "b2"))
+	assert.Equal(t, "a1", c.LastContext)
+
+	// the namespace is kept on a failed switch
+	commitFile = func(path string, data []byte, perm os.FileMode) error {
+		if filepath.Base(path) == ".state" {
+			return errors.New("disk full")
+		}
+		return replaceFile(path, data, perm)
+	}
+	t.Cleanup(func() { commitFile = replaceFile })
+	assert.ErrorIs(t, c.SwitchToNamespace(b, "b1", "web"), ErrSwitchContext)
+	assert.Equal(t, "", namespaceOf(b.Path, "b1"))
+	ctx, _, _ := b.KubeConfig.GetContextBy("b1")
+	assert.Equal(t, "", ctx.Namespace, "namespace in memory")
+	assert.Equal(t, "b2", c.CurrentContext)
+}
+
+func TestConfig_SwitchTo_afterCommit(t *testing.T) {
+	c, _ := switchSetup(t)
+	// synced input files, whose fingerprint cannot be computed again
+	c.digest = "fingerprint"
+	c.GlobalConfig = filepath.Join(c.Dir, "missing.jsonnet")
+	if err := c.SaveFingerprint(); err != nil {
+		t.Fatal(err)
+	}
+	err := c.SwitchTo(c.KubeConfs[1], "b2")
+	assert.ErrorIs(t, err, ErrParseConfig)
+	assert.NotErrorIs(t, err, ErrSwitchContext)
+	assert.Equal(t, "b2", c.CurrentContext)
+	assert.Equal(t, "b2", c.KubeConfs[1].KubeConfig.CurrentContext)
+}
+
+func TestConfig_SwitchToNamespace_contextsFile(t *testing.T) {
+	c, _ := switchSetup(t)
+	b := c.KubeConfs[1]
+	b.ContextFile = filepath.Join(c.Dir, "contexts_b.yaml")
+	b.Contexts = []*ContextEntry{{Name: "b1"}, {Name: "b2", Namespace: "default"}}
+
+	done := make(chan error)
+	go func() { done <- c.SwitchToNamespace(b, "b2", "monitoring") }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config.SwitchToNamespace() hangs")
+	}
+
+	f, err := os.ReadFile(b.ContextFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contexts := []*ContextEntry{}
+	if err := yaml.Unmarshal(f, &contexts); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, contexts, 2) {
+		assert.Equal(t, "monitoring", contexts[1].Namespace)
+	}
+	assert.Equal(t, "b2", c.CurrentContext)
+
+	// a failed switch restores the contexts file
+	commitFile = func(path string, data []byte, perm os.FileMode) error {
+		if filepath.Base(path) == ".state" {
+			return errors.New("disk full")
+		}
+		return replaceFile(path, data, perm)
+	}
+	t.Cleanup(func() { commitFile = replaceFile })
+	assert.ErrorIs(t, c.SwitchToNamespace(b, "b2", "web"), ErrSwitchContext)
+	got, err := os.ReadFile(b.ContextFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(f), string(got))
+	assert.Equal(t, "monitoring", b.Contexts[1].Namespace, "namespace in memory")
+
+	// a failed write of the contexts file restores the kube config
+	kube, err := os.ReadFile(b.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile = func(path string, data []byte, perm os.FileMode) error {
+		if path == b.ContextFile {
+			return errors.New("disk full")
+		}
+		return replaceFile(path, data, perm)
+	}
+	assert.ErrorIs(t, c.SwitchToNamespace(b, "b2", "web"), ErrSwitchContext)
+	got, err = os.ReadFile(b.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(kube), string(got))
+	assert.Equal(t, "monitoring", b.namespaceOf("b2"), "namespace in memory")
+}
@@ -0,0 +1,267 @@
+package k8sctx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// syncFile is the file inside the config dir, which holds the fingerprint of
+// the input files at the last sync.
+const syncFile = ".sync"
+
+type (
+	// NamespaceChange describes the namespace of a context, which is written
+	// into the kube config by Sync.
+	NamespaceChange struct {
+		Context string `json:"context"`
+		From    string `json:"from"`
+		To      string `json:"to"`
+	}
+	// SyncReport lists the changes of a single kube config and its contexts
+	// file made by Sync.
+	SyncReport struct {
+		Alias       string            `json:"alias"`
+		KubeConfig  string            `json:"kubeconfig"`
+		ContextFile string            `json:"contextFile"`
+		Namespaces  []NamespaceChange `json:"namespaces"`
+		Contexts    []ContextChange   `json:"contexts"`
+		// FileNamespaces are the namespaces taken from the kube config and
+		// written into the contexts file (see DriftKubeConfigWins).
+		FileNamespaces []NamespaceChange `json:"fileNamespaces"`
+		// Drifts are the namespace drifts, which are left unresolved.
+		Drifts []NamespaceDrift `json:"drifts"`
+	}
+)
+
+// Changed returns true if the report holds at least one change.
+func (r SyncReport) Changed() bool {
+	return len(r.Namespaces) > 0 || len(r.Contexts) > 0 || len(r.FileNamespaces) > 0
+}
+
+// String returns the changes in a diff like format:
+//
+//	~ /home/user/.kube/config
+//	  ~ namespace of 'dev': default -> monitoring
+//	~ /home/user/.config/ktx/contexts_dev.yaml
+//	  + added context 'lab'
+//	! namespace drift of 'prod': kubeconfig: default, contexts file: <none>, config: web
+func (r SyncReport) String() string {
+	lines := []string{}
+	if len(r.Namespaces) > 0 {
+		lines = append(lines, "~ "+r.KubeConfig)
+		for _, ns := range r.Namespaces {
+			lines = append(lines, fmt.Sprintf("  ~ namespace of '%s': %s -> %s", ns.Context, orNone(ns.From), ns.To))
+		}
+	}
+	if len(r.Contexts) > 0 || len(r.FileNamespaces) > 0 {
+		lines = append(lines, "~ "+r.ContextFile)
+		for _, ch := range r.Contexts {
+			sign := "~"
+			switch ch.Kind {
+			case ContextAdded, ContextRestored:
+				sign = "+"
+			case ContextRemoved, ContextStale:
+				sign = "-"
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s", sign, ch))
+		}
+		for _, ns := range r.FileNamespaces {
+			lines = append(lines, fmt.Sprintf("  ~ namespace of '%s': %s -> %s", ns.Context, orNone(ns.From), orNone(ns.To)))
+		}
+	}
+	for _, d := range r.Drifts {
+		lines = append(lines, "! "+d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// Sync reconciles the contexts files with the kube configs (see SyncContexts)
+// and resolves the namespace drifts according to the NamespaceDrift policy.
+// With dryRun nothing is written and the reports list the changes, which
+// would be made.
+func (c *Config) Sync(dryRun bool) ([]SyncReport, error) {
+	if err := validOrphans(c.OrphanedContexts); err != nil {
+		return nil, err
+	}
+	if err := validDrift(c.NamespaceDrift); err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		unlock, err := c.Lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	if err := c.GetState(); err != nil {
+		return nil, err
+	}
+	originsChanged := false
+	reports := []SyncReport{}
+	for _, cnf := range c.KubeConfs {
+		r := SyncReport{Alias: cnf.Alias, KubeConfig: cnf.KubeConfig.Path, ContextFile: cnf.ContextFile}
+		target := cnf
+		origins := c.State.Origins[cnf.Path]
+		if dryRun {
+			target = &KubeConf{Alias: cnf.Alias, ContextFile: cnf.ContextFile, KubeConfig: cnf.KubeConfig, origins: origins}
+			for _, ctx := range cnf.Contexts {
+				target.Contexts = append(target.Contexts, ctx.Clone())
+			}
+			r.Contexts = cnf.KubeConfig.reconcile(target, c.OrphanedContexts)
+		} else {
+			cnf.origins = origins
+			changes, err := cnf.KubeConfig.SyncContexts(cnf, c.OrphanedContexts)
+			if err != nil {
+				return nil, fmt.Errorf("sync contexts: %w", err)
+			}
+			r.Contexts = changes
+			if !maps.Equal(origins, cnf.origins) {
+				c.State.setOrigins(cnf.Path, cnf.origins)
+				originsChanged = true
+			}
+		}
+		if err := target.syncDrifts(&r, c.NamespaceDrift, dryRun); err != nil {
+			return nil, fmt.Errorf("sync namespaces: %w", err)
+		}
+		reports = append(reports, r)
+	}
+	if originsChanged {
+		if err := c.saveState(); err != nil {
+			return nil, fmt.Errorf("save origins: %w", err)
+		}
+	}
+	return reports, nil
+}
+
+// SyncInMemory reconciles the contexts with the kube configs like Sync, but
+// only in memory: no file is written. Read-only commands use it to see the
+// contexts of the kube configs before the next sync. Added contexts have no
+// fields of the config.jsonnet.
+func (c *Config) SyncInMemory() error {
+	if err := c.GetState(); err != nil {
+		return err
+	}
+	for _, cnf := range c.KubeConfs {
+		if cnf.KubeConfig == nil {
+			continue
+		}
+		cnf.origins = c.State.Origins[cnf.Path]
+		cnf.KubeConfig.reconcile(cnf, c.OrphanedContexts)
+	}
+	return nil
+}
+
+// syncDrifts resolves the namespace drifts of the kube config according to
+// the policy and adds them to the report. A forced drift, which the kube
+// config should win, is only reported.
+func (k *KubeConf) syncDrifts(r *SyncReport, policy string, dryRun bool) error {
+	drifts, err := k.NamespaceDrifts()
+	if err != nil {
+		return err
+	}
+	for _, d := range drifts {
+		winner := policy
+		if winner == "" {
+			// a namespace changed via kubectl is kept, a missing one is filled in
+			winner = DriftKubeConfigWins
+			if d.KubeConfig == "" {
+				winner = DriftConfigWins
+			}
+		}
+		switch {
+		case winner == DriftAsk, winner == DriftKubeConfigWins && d.Forced():
+			r.Drifts = append(r.Drifts, d)
+			continue
+		case winner == DriftKubeConfigWins:
+			r.FileNamespaces = append(r.FileNamespaces, NamespaceChange{Context: d.Context, From: d.ContextFile, To: d.KubeConfig})
+		default:
+			r.Namespaces = append(r.Namespaces, NamespaceChange{Context: d.Context, From: d.KubeConfig, To: d.Config})
+		}
+		if !dryRun {
+			if err := k.ResolveDrift(d, winner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fingerprint returns a hash over the evaluated config.jsonnet, which
+// includes the contexts files, and the content of the kube configs.
+func (c *Config) fingerprint(evaluated string) string {
+	h := sha256.New()
+	h.Write([]byte(evaluated))
+	for _, cnf := range c.KubeConfs {
+		if cnf.KubeConfig == nil {
+			continue
+		}
+		fmt.Fprintf(h, "\x00%s\x00", cnf.KubeConfig.Path)
+		h.Write(cnf.KubeConfig.raw)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// KeepSynced runs fn, which changes the input files, under the lock of the
+// config dir. fn reports whether it wrote one of the input files. If the
+// input files were synced before, their fingerprint is stored again after
+// such a write, so that the changes made by fn don't cause the next sync.
+func (c *Config) KeepSynced(fn func() (bool, error)) error {
+	unlock, err := c.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	synced := c.Synced()
+	written, err := fn()
+	if !synced || !written {
+		return err
+	}
+	after, ferr := c.freshFingerprint()
+	if ferr != nil {
+		return errors.Join(err, ferr)
+	}
+	c.digest = after
+	return errors.Join(err, c.SaveFingerprint())
+}
+
+// freshFingerprint reads the input files again and returns their fingerprint.
+func (c *Config) freshFingerprint() (string, error) {
+	fresh, err := Get(c.GlobalConfig)
+	if err != nil {
+		return "", err
+	}
+	return fresh.digest, nil
+}
+
+// Synced returns true if the input files didn't change since the last sync.
+func (c *Config) Synced() bool {
+	f, err := os.ReadFile(filepath.Join(c.Dir, syncFile))
+	return err == nil && c.digest != "" && strings.TrimSpace(string(f)) == c.digest
+}
+
+// SaveFingerprint stores the fingerprint of the input files, so that Synced
+// returns true until one of them changes.
+func (c *Config) SaveFingerprint() error {
+	if c.digest == "" {
+		return errors.New("no fingerprint available")
+	}
+	unlock, err := c.lock.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeFile(filepath.Join(c.Dir, syncFile), []byte(c.digest+"\n"), FileMode)
+}
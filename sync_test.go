@@ -0,0 +1,187 @@
+package k8sctx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Sync(t *testing.T) {
+	setup := func(t *testing.T) *Config {
+		c, _ := switchSetup(t)
+		kc := c.KubeConfs[1]
+		kc.ContextFile = filepath.Join(c.Dir, "contexts_b.yaml")
+		kc.Contexts = []*ContextEntry{
+			{Name: "b1", Namespace: "monitoring"},
+			{Name: "gone", Namespace: "default"},
+		}
+		c.KubeConfs = []*KubeConf{kc}
+		return c
+	}
+	want := func(c *Config) []SyncReport {
+		return []SyncReport{
+			{
+				Alias:       "b",
+				KubeConfig:  c.KubeConfs[0].Path,
+				ContextFile: c.KubeConfs[0].ContextFile,
+				Namespaces:  []NamespaceChange{{Context: "b1", From: "", To: "monitoring"}},
+				Contexts: []ContextChange{
+					{Kind: ContextAdded, Name: "b2"},
+					{Kind: ContextStale, Name: "gone"},
+				},
+			},
+		}
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		c := setup(t)
+		before, err := os.ReadFile(c.KubeConfs[0].Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports, err := c.Sync(true)
+		if err != nil {
+			t.Fatalf("Config.Sync() error = %v", err)
+		}
+		assert.Equal(t, want(c), reports)
+		assert.NoFileExists(t, c.KubeConfs[0].ContextFile)
+		assert.Len(t, c.KubeConfs[0].Contexts, 2, "contexts were changed")
+		after, err := os.ReadFile(c.KubeConfs[0].Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, string(before), string(after))
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		c := setup(t)
+		reports, err := c.Sync(false)
+		if err != nil {
+			t.Fatalf("Config.Sync() error = %v", err)
+		}
+		assert.Equal(t, want(c), reports)
+		assert.FileExists(t, c.KubeConfs[0].ContextFile)
+		k, err := GetKubeConfig(c.KubeConfs[0].Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, _, _ := k.GetContextBy("b1")
+		assert.Equal(t, "monitoring", ctx.Namespace)
+		assert.Equal(t, "~ "+c.KubeConfs[0].Path+"\n"+
+			"  ~ namespace of 'b1': <none> -> monitoring\n"+
+			"~ "+c.KubeConfs[0].ContextFile+"\n"+
+			"  + added context 'b2'\n"+
+			"  - marked context 'gone' as stale", reports[0].String())
+
+		reports, err = c.Sync(false)
+		assert.NoError(t, err)
+		assert.False(t, reports[0].Changed())
+	})
+}
+
+func TestConfig_Synced(t *testing.T) {
+	c, _ := switchSetup(t)
+	c.digest = c.fingerprint("{}")
+	assert.False(t, c.Synced())
+	assert.NoError(t, c.SaveFingerprint())
+	assert.True(t, c.Synced())
+
+	if err := c.KubeConfs[0].KubeConfig.RemoveCurrentContext(); err != nil {
+		t.Fatal(err)
+	}
+	c.digest = c.fingerprint("{}")
+	assert.False(t, c.Synced(), "changed kube config")
+	c.digest = ""
+	assert.False(t, c.Synced(), "missing fingerprint")
+}
+
+func TestConfig_KeepSynced(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kube": "contexts:\n- name: x1\n  context:\n    cluster: x\n    user: x\n    namespace: web\n" +
+			"- name: x2\n  context:\n    cluster: x\n    user: x\ncurrent-context: x2\n",
+		"contexts_x.yaml": "- name: x1\n  namespace: web\n- name: x2\n",
+		"config.jsonnet": "{ kube_configs: [{ alias: 'x', path: '" + filepath.Join(dir, "kube") + "', " +
+			"contexts: std.parseYaml(importstr 'contexts_x.yaml') }] }",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := Get(filepath.Join(dir, "config.jsonnet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, c.SaveFingerprint())
+	if !assert.True(t, c.Synced()) {
+		return
+	}
+
+	assert.NoError(t, c.SwitchToNamespace(c.KubeConfs[0], "x1", "monitoring"))
+	got, err := Get(c.GlobalConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "monitoring", got.KubeConfs[0].Contexts[0].Namespace, "contexts file not updated")
+	assert.True(t, got.Synced(), "own switch causes a sync")
+
+	if err := os.Remove(filepath.Join(dir, syncFile)); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, c.SwitchTo(c.KubeConfs[0], "x2"))
+	got, err = Get(c.GlobalConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, got.Synced(), "switch marks unsynced files as synced")
+
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveFingerprint(); err != nil {
+		t.Fatal(err)
+	}
+	// a write, which is not reported, keeps the old fingerprint
+	assert.NoError(t, c.KeepSynced(func() (bool, error) {
+		return false, os.WriteFile(filepath.Join(dir, "kube"), []byte(files["kube"]), 0600)
+	}))
+	got, err = Get(c.GlobalConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, got.Synced(), "fingerprint computed without a write")
+}
+
+func TestConfig_Sync_rename(t *testing.T) {
+	c, _ := switchSetup(t)
+	kc := c.KubeConfs[0]
+	kc.ContextFile = filepath.Join(c.Dir, "contexts_a.yaml")
+	kc.Contexts = []*ContextEntry{{Name: "a1", Alias: "x"}}
+	c.KubeConfs = []*KubeConf{kc}
+	if _, err := c.Sync(false); err != nil {
+		t.Fatalf("Config.Sync() error = %v", err)
+	}
+	s := &State{Filename: c.Filename}
+	assert.NoError(t, s.load())
+	assert.Equal(t, map[string]Origin{"a1": {Cluster: "a", User: "a"}}, s.Origins[kc.Path])
+	file, err := os.ReadFile(kc.ContextFile)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(file), "cluster", "origin written into the contexts file")
+
+	renamed := "contexts:\n- name: a2\n  context:\n    cluster: a\n    user: a\ncurrent-context: a2\n"
+	if err := os.WriteFile(kc.Path, []byte(renamed), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if kc.KubeConfig, err = GetKubeConfig(kc.Path); err != nil {
+		t.Fatal(err)
+	}
+	reports, err := c.Sync(false)
+	if err != nil {
+		t.Fatalf("Config.Sync() error = %v", err)
+	}
+	assert.Equal(t, []ContextChange{{Kind: ContextRenamed, Name: "a2", From: "a1"}}, reports[0].Contexts)
+	assert.Equal(t, []*ContextEntry{{Name: "a2", Alias: "x"}}, kc.Contexts)
+}
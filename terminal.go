@@ -0,0 +1,81 @@
+package k8sctx
+
+import (
+	"os"
+	"sort"
+)
+
+// terminalsSize is the maximum number of terminals in the state. The
+// terminals, which switched least recently, are dropped first.
+const terminalsSize = 20
+
+// Terminal is the part of the state of a single terminal. It is used by
+// "ktx -" to jump back to the context previously used in the terminal,
+// even if other terminals switched the context in the meantime.
+type Terminal struct {
+	// Current is the context the terminal switched to last.
+	Current HistoryEntry `yaml:"current"`
+	// Last is the context the terminal used before.
+	Last HistoryEntry `yaml:"last,omitempty"`
+}
+
+// TerminalID returns the id of the terminal of the current process: the
+// KTX_SESSION variable or, if it is not set, the device of the controlling
+// TTY. It is empty, if neither is known.
+func TerminalID() string {
+	if id := os.Getenv(SessionEnv); id != "" {
+		return "session:" + id
+	}
+	return ttyID()
+}
+
+// track stores the current context as the one of the terminal. The previous
+// context of the terminal becomes its last one. A terminal without a
+// previous context gets the last context of the state. The terminals are
+// copied, so that a copy of the state stays unchanged.
+func (s *State) track(terminal string) {
+	if terminal == "" || s.CurrentContext == "" {
+		return
+	}
+	prev, known := s.Terminals[terminal]
+	last := prev.Current
+	if !known {
+		last = HistoryEntry{KubeConfig: s.LastConf, Context: s.LastContext}
+	}
+	if last.KubeConfig == s.LastConf && last.Context == s.LastContext {
+		last.Namespace = s.LastNamespace
+	}
+	if last.KubeConfig == s.CurrentConf && last.Context == s.CurrentContext {
+		last = prev.Last
+	}
+	current := HistoryEntry{KubeConfig: s.CurrentConf, Context: s.CurrentContext, Namespace: s.CurrentNamespace, Time: now()}
+
+	terminals := make(map[string]Terminal, len(s.Terminals)+1)
+	for k, v := range s.Terminals {
+		terminals[k] = v
+	}
+	terminals[terminal] = Terminal{Current: current, Last: last}
+	if len(terminals) > terminalsSize {
+		ids := make([]string, 0, len(terminals))
+		for id := range terminals {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return terminals[ids[i]].Current.Time.After(terminals[ids[j]].Current.Time)
+		})
+		for _, id := range ids[terminalsSize:] {
+			delete(terminals, id)
+		}
+	}
+	s.Terminals = terminals
+}
+
+// Previous returns the context previously used in the terminal. Without a
+// previous context of the terminal, the last context of the state is
+// returned.
+func (s *State) Previous(terminal string) HistoryEntry {
+	if t, ok := s.Terminals[terminal]; ok && terminal != "" && t.Last.Context != "" {
+		return t.Last
+	}
+	return HistoryEntry{KubeConfig: s.LastConf, Context: s.LastContext, Namespace: s.LastNamespace}
+}
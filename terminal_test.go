@@ -0,0 +1,63 @@
+package k8sctx
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_SwitchTo_terminals(t *testing.T) {
+	c, _ := switchSetup(t)
+	a, b, cc := c.KubeConfs[0], c.KubeConfs[1], c.KubeConfs[2]
+
+	c.Terminal = "A"
+	assert.NoError(t, c.SwitchTo(b, "b1"))
+	c.Terminal = "B"
+	assert.NoError(t, c.SwitchTo(cc, "c1"))
+
+	got := &State{Filename: c.Filename}
+	assert.NoError(t, got.load())
+	assert.Equal(t, "b1", got.Previous("").Context, "global state")
+	assert.Equal(t, "a1", got.Previous("A").Context, "terminal A")
+	assert.Equal(t, a.Path, got.Previous("A").KubeConfig)
+	assert.Equal(t, "b1", got.Previous("B").Context, "terminal B falls back to the global state")
+	assert.Equal(t, "b1", got.Previous("unknown").Context, "unknown terminal")
+
+	c.Terminal = "A"
+	assert.NoError(t, c.SwitchTo(a, "a1"))
+	assert.NoError(t, got.load())
+	assert.Equal(t, "b1", got.Previous("A").Context, "terminal A jumps back and forth")
+}
+
+func TestState_track(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Minute)
+	}
+	t.Cleanup(func() { now = time.Now })
+
+	s := &State{}
+	s.update("a", "a1", "")
+	s.track("")
+	assert.Nil(t, s.Terminals, "without terminal")
+
+	s.update("b", "b1", "web")
+	s.track("A")
+	backup := *s
+	s.update("b", "b1", "monitoring")
+	s.track("A")
+	assert.Equal(t, "a1", s.Terminals["A"].Last.Context, "switch to the current context keeps the last one")
+	assert.Equal(t, "monitoring", s.Terminals["A"].Current.Namespace)
+	assert.Equal(t, "web", backup.Terminals["A"].Current.Namespace, "copy of the state was changed")
+
+	for i := 0; i < terminalsSize+5; i++ {
+		s.update("c", fmt.Sprintf("c%d", i), "")
+		s.track(fmt.Sprintf("T%d", i))
+	}
+	assert.Len(t, s.Terminals, terminalsSize)
+	assert.NotContains(t, s.Terminals, "A", "least recently used terminal is dropped")
+}
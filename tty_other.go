@@ -0,0 +1,9 @@
+//go:build !unix
+
+package k8sctx
+
+// ttyID is not supported on platforms without unix devices. The terminal is
+// only known from the KTX_SESSION variable there.
+func ttyID() string {
+	return ""
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package k8sctx
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ttyID returns the device number of the terminal of stdin or stderr. Stdout
+// is skipped, because it is captured by the shell function of "ktx init".
+func ttyID() string {
+	null, _ := os.Stat(os.DevNull)
+	for _, f := range []*os.File{os.Stdin, os.Stderr} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 || (null != nil && os.SameFile(info, null)) {
+			continue
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			return fmt.Sprintf("tty:%d", st.Rdev)
+		}
+	}
+	return ""
+}